@@ -2,6 +2,8 @@ package auth
 
 import (
 	"errors"
+	"fmt"
+	"github.com/alexandria-oss/core/exception"
 	"github.com/dgrijalva/jwt-go"
 	"strings"
 )
@@ -42,3 +44,25 @@ func ParseBearerJWT(bearer string) (*IdentityClaims, error) {
 
 	return claims, nil
 }
+
+// VerifyBearerJWT returns JWT claims from JWT bearer token once its HMAC signature is verified using
+// the given secret, returns exception.Unauthenticated if the token is missing, expired or invalid
+func VerifyBearerJWT(bearer, secret string) (*IdentityClaims, error) {
+	tokenStr := strings.TrimPrefix(bearer, "Bearer ")
+	if tokenStr == "" || tokenStr == bearer || secret == "" {
+		return nil, exception.NewErrorDescription(exception.Unauthenticated, "missing bearer token")
+	}
+
+	claims := new(IdentityClaims)
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, exception.NewErrorDescription(exception.Unauthenticated, "invalid bearer token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/alexandria-oss/core/exception"
+	"golang.org/x/sync/singleflight"
+)
+
+// Entry flags, every stored value starts with one of them
+const (
+	flagValue    byte = 'v'
+	flagNotFound byte = 'n'
+)
+
+// Cache Typed cache-aside layer on top of a Store
+type Cache struct {
+	store       Store
+	codec       Codec
+	ttl         time.Duration
+	jitter      float64
+	negativeTTL time.Duration
+	group       singleflight.Group
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// Option Sets a cache option
+type Option func(*Cache)
+
+// WithCodec sets the values serialization, JSON by default
+func WithCodec(codec Codec) Option {
+	return func(c *Cache) {
+		c.codec = codec
+	}
+}
+
+// WithTTL sets the default time to live, 5 minutes by default
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithJitter randomly extends TTLs up to the given fraction (e.g. 0.1 adds up to 10%), so keys
+// cached together do not expire together
+func WithJitter(fraction float64) Option {
+	return func(c *Cache) {
+		c.jitter = fraction
+	}
+}
+
+// WithNegativeTTL caches exception.EntityNotFound loader results for ttl, disabled if zero
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.negativeTTL = ttl
+	}
+}
+
+// New returns a cache on top of the given store
+func New(store Store, opts ...Option) *Cache {
+	c := &Cache{
+		store:  store,
+		codec:  JSON,
+		ttl:    5 * time.Minute,
+		jitter: 0.1,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Get decodes the key's value into v, returns ErrMiss if not cached or exception.EntityNotFound if
+// the entity is known to be missing
+func (c *Cache) Get(ctx context.Context, key string, v interface{}) error {
+	data, err := c.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return c.decode(key, data, v)
+}
+
+// Set caches v, the default TTL is used if ttl is zero
+func (c *Cache) Set(ctx context.Context, key string, v interface{}, ttl time.Duration) error {
+	data, err := c.encode(v)
+	if err != nil {
+		return err
+	}
+
+	if ttl == 0 {
+		ttl = c.ttl
+	}
+
+	return c.store.Set(ctx, key, data, c.withJitter(ttl))
+}
+
+// Delete evicts the given keys
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	return c.store.Delete(ctx, keys...)
+}
+
+// GetOrLoad decodes the key's value into v, calling load on misses and caching its result. Concurrent
+// misses of the same key share a single load call. Store failures fall back to load
+//	* The shared load keeps ctx values but is not canceled by any caller, each caller stops waiting
+//	when its own ctx is done
+func (c *Cache) GetOrLoad(ctx context.Context, key string, v interface{}, ttl time.Duration,
+	load func(ctx context.Context) (interface{}, error)) error {
+	data, err := c.store.Get(ctx, key)
+	if err == nil {
+		return c.decode(key, data, v)
+	}
+
+	loadCtx := detachedContext{parent: ctx}
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		value, err := load(loadCtx)
+		if errors.Is(err, exception.EntityNotFound) && c.negativeTTL > 0 {
+			_ = c.store.Set(loadCtx, key, []byte{flagNotFound}, c.withJitter(c.negativeTTL))
+			return nil, err
+		} else if err != nil {
+			return nil, err
+		}
+
+		data, err := c.encode(value)
+		if err != nil {
+			return nil, err
+		}
+		if ttl == 0 {
+			ttl = c.ttl
+		}
+		_ = c.store.Set(loadCtx, key, data, c.withJitter(ttl))
+
+		return data, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return res.Err
+		}
+		return c.decode(key, res.Val.([]byte), v)
+	}
+}
+
+// detachedContext Keeps the parent's values, but neither its deadline nor its cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+func (c *Cache) encode(v interface{}) ([]byte, error) {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{flagValue}, data...), nil
+}
+
+func (c *Cache) decode(key string, data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return ErrMiss
+	}
+
+	switch data[0] {
+	case flagValue:
+		return c.codec.Unmarshal(data[1:], v)
+	case flagNotFound:
+		return exception.NewErrorDescription(exception.EntityNotFound, fmt.Sprintf("cached entity %s not found", key))
+	default:
+		return ErrMiss
+	}
+}
+
+func (c *Cache) withJitter(ttl time.Duration) time.Duration {
+	if c.jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ttl + time.Duration(c.rand.Float64()*c.jitter*float64(ttl))
+}
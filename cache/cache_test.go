@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexandria-oss/core/exception"
+	"github.com/stretchr/testify/assert"
+)
+
+type author struct {
+	ID   string
+	Name string
+	Year int
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	for _, codec := range []Codec{JSON, MsgPack, Gob} {
+		c := New(NewLRUStore(10), WithCodec(codec))
+		assert.Nil(t, c.Set(ctx, "author:1", &author{ID: "1", Name: "Mario Vargas Llosa", Year: 1936}, 0))
+
+		a := new(author)
+		assert.Nil(t, c.Get(ctx, "author:1", a))
+		assert.Equal(t, "Mario Vargas Llosa", a.Name)
+		assert.Equal(t, 1936, a.Year)
+
+		assert.Nil(t, c.Delete(ctx, "author:1"))
+		assert.Equal(t, ErrMiss, c.Get(ctx, "author:1", a))
+	}
+}
+
+func TestCache_GetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	c := New(NewLRUStore(10), WithNegativeTTL(time.Minute))
+
+	// Concurrent misses share a single load
+	var loads int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return &author{ID: "1", Name: "Octavio Paz"}, nil
+	}
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a := new(author)
+			assert.Nil(t, c.GetOrLoad(ctx, "author:1", a, 0, load))
+			assert.Equal(t, "Octavio Paz", a.Name)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	a := new(author)
+	assert.Nil(t, c.GetOrLoad(ctx, "author:1", a, 0, load))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	// Missing entities are cached too
+	notFound := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		return nil, exception.EntityNotFound
+	}
+	for i := 0; i < 2; i++ {
+		err := c.GetOrLoad(ctx, "author:2", a, 0, notFound)
+		assert.True(t, errors.Is(err, exception.EntityNotFound))
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+
+	// Callers leaving do not cancel the shared load
+	release = make(chan struct{})
+	cancelable := func(ctx context.Context) (interface{}, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-release:
+			return &author{ID: "3", Name: "Rosario Castellanos"}, nil
+		}
+	}
+	firstCtx, cancel := context.WithCancel(ctx)
+	errs := make(chan error)
+	go func() {
+		errs <- c.GetOrLoad(firstCtx, "author:3", new(author), 0, cancelable)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	go func() {
+		a := new(author)
+		err := c.GetOrLoad(ctx, "author:3", a, 0, cancelable)
+		assert.Equal(t, "Rosario Castellanos", a.Name)
+		errs <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	assert.Equal(t, context.Canceled, <-errs)
+	close(release)
+	assert.Nil(t, <-errs)
+}
+
+func TestLRUStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLRUStore(2)
+	assert.Nil(t, store.Set(ctx, "a", []byte("1"), 0))
+	assert.Nil(t, store.Set(ctx, "b", []byte("2"), 0))
+	_, err := store.Get(ctx, "a")
+	assert.Nil(t, err)
+
+	// b is the least recently used key
+	assert.Nil(t, store.Set(ctx, "c", []byte("3"), 0))
+	_, err = store.Get(ctx, "b")
+	assert.Equal(t, ErrMiss, err)
+	assert.Equal(t, 2, store.Len())
+
+	assert.Nil(t, store.Set(ctx, "d", []byte("4"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, err = store.Get(ctx, "d")
+	assert.Equal(t, ErrMiss, err)
+
+	// L2 hits are kept in L1
+	l1, l2 := NewLRUStore(10), NewLRUStore(10)
+	tiered := NewTieredStore(l1, l2, time.Minute)
+	assert.Nil(t, l2.Set(ctx, "a", []byte("1"), 0))
+	value, err := tiered.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), value)
+	value, err = l1.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), value)
+}
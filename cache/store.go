@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+)
+
+// ErrMiss The key is not cached
+var ErrMiss = errors.New("cache miss")
+
+// Store Stores encoded values
+type Store interface {
+	// Get returns the key's value or ErrMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value, a zero ttl keeps it until evicted
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// RedisStore Store backed by Redis, shared by every replica
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore returns a Redis store prepending prefix to every key
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisStore) Get(_ context.Context, key string) ([]byte, error) {
+	value, err := s.client.Get(s.prefix + key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrMiss
+	}
+
+	return value, err
+}
+
+func (s *RedisStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(s.prefix+key, value, ttl).Err()
+}
+
+func (s *RedisStore) Delete(_ context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixed := make([]string, 0, len(keys))
+	for _, k := range keys {
+		prefixed = append(prefixed, s.prefix+k)
+	}
+
+	return s.client.Del(prefixed...).Err()
+}
+
+// LRUStore In-memory store evicting the least recently used keys, meant for tests and as a
+// per-replica L1 cache
+type LRUStore struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRUStore returns an in-memory store holding up to capacity keys
+func NewLRUStore(capacity int) *LRUStore {
+	return &LRUStore{
+		capacity: capacity,
+		items:    make(map[string]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+func (s *LRUStore) Get(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.items[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := e.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		s.remove(e)
+		return nil, ErrMiss
+	}
+	s.order.MoveToFront(e)
+
+	return entry.value, nil
+}
+
+func (s *LRUStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := &lruEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	if e, ok := s.items[key]; ok {
+		e.Value = entry
+		s.order.MoveToFront(e)
+		return nil
+	}
+
+	s.items[key] = s.order.PushFront(entry)
+	if s.capacity > 0 && s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+
+	return nil
+}
+
+func (s *LRUStore) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range keys {
+		if e, ok := s.items[k]; ok {
+			s.remove(e)
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of stored keys, expired keys included until accessed or evicted
+func (s *LRUStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *LRUStore) remove(e *list.Element) {
+	s.order.Remove(e)
+	delete(s.items, e.Value.(*lruEntry).key)
+}
+
+// TieredStore Reads from a fast local store (L1) before a shared one (L2), values found in L2 are
+// kept in L1 for l1TTL
+type TieredStore struct {
+	l1    Store
+	l2    Store
+	l1TTL time.Duration
+}
+
+// NewTieredStore returns a two-level store, l1TTL bounds how stale L1 values might get
+func NewTieredStore(l1, l2 Store, l1TTL time.Duration) *TieredStore {
+	return &TieredStore{
+		l1:    l1,
+		l2:    l2,
+		l1TTL: l1TTL,
+	}
+}
+
+func (s *TieredStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, err := s.l1.Get(ctx, key); err == nil {
+		return value, nil
+	}
+
+	value, err := s.l2.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.l1.Set(ctx, key, value, s.l1TTL)
+
+	return value, nil
+}
+
+func (s *TieredStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.l2.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	l1TTL := s.l1TTL
+	if ttl > 0 && ttl < l1TTL {
+		l1TTL = ttl
+	}
+
+	return s.l1.Set(ctx, key, value, l1TTL)
+}
+
+func (s *TieredStore) Delete(ctx context.Context, keys ...string) error {
+	if err := s.l2.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	return s.l1.Delete(ctx, keys...)
+}
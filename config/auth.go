@@ -2,16 +2,17 @@ package config
 
 import "github.com/spf13/viper"
 
-type auth struct {
+// Auth Authentication settings
+type Auth struct {
 	JWTSecret string
 }
 
-func init() {
-	viper.SetDefault("alexandria.security.auth.jwt.secret", "example_secret")
+func setAuthDefaults(v *viper.Viper) {
+	v.SetDefault("alexandria.security.auth.jwt.secret", "example_secret")
 }
 
-func newAuthConfig() auth {
-	return auth{
-		JWTSecret: viper.GetString("alexandria.security.auth.jwt.secret"),
+func newAuthConfig(v *viper.Viper) Auth {
+	return Auth{
+		JWTSecret: v.GetString("alexandria.security.auth.jwt.secret"),
 	}
 }
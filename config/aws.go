@@ -2,19 +2,20 @@ package config
 
 import "github.com/spf13/viper"
 
-type aws struct {
+// AWS Amazon Web Services settings
+type AWS struct {
 	CognitoPoolID   string
 	CognitoClientID string
 }
 
-func init() {
-	viper.SetDefault("alexandria.cloud.aws.cognito.pool", "example_pool_id")
-	viper.SetDefault("alexandria.cloud.aws.cognito.client", "example_client_id")
+func setAWSDefaults(v *viper.Viper) {
+	v.SetDefault("alexandria.cloud.aws.cognito.pool", "example_pool_id")
+	v.SetDefault("alexandria.cloud.aws.cognito.client", "example_client_id")
 }
 
-func newAWSConfig() aws {
-	return aws{
-		CognitoPoolID:   viper.GetString("alexandria.cloud.aws.cognito.pool"),
-		CognitoClientID: viper.GetString("alexandria.cloud.aws.cognito.client"),
+func newAWSConfig(v *viper.Viper) AWS {
+	return AWS{
+		CognitoPoolID:   v.GetString("alexandria.cloud.aws.cognito.pool"),
+		CognitoClientID: v.GetString("alexandria.cloud.aws.cognito.client"),
 	}
 }
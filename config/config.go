@@ -7,70 +7,85 @@ import (
 )
 
 // Kernel Alexandria kernel configuration struct
-// Generates required OS env variables
 type Kernel struct {
-	Transport transport
-	Tracing   tracing
+	Transport  Transport
+	Resiliency Resiliency
+	Tracing    Tracing
+	Logging    Logging
 
-	EventBus eventBus
+	EventBus EventBus
 
-	Docstore docstore
-	DBMS     dbms
-	InMemory inMemory
+	Docstore Docstore
+	DBMS     DBMS
+	InMemory InMemory
 
-	AWS aws
+	AWS AWS
 
-	Auth auth
+	Auth     Auth
+	Secrets  Secrets
+	Features Features
 
 	Version string
 	Service string
+	// Profile Deployment profile (dev, staging or prod)
+	Profile string
+
+	// Sections Custom sections registered using WithSections, keyed by root key
+	Sections map[string]interface{}
 }
 
-func init() {
+// setKernelDefaults sets the default values of every kernel section
+func setKernelDefaults(v *viper.Viper) {
 	// Service info
-	viper.SetDefault("alexandria.info.version", "0.1.0")
-	viper.SetDefault("alexandria.info.service", "example-service")
+	v.SetDefault("alexandria.info.version", "0.1.0")
+	v.SetDefault("alexandria.info.service", "example-service")
+	v.SetDefault("alexandria.info.profile", ProfileDevelopment)
+
+	setTransportDefaults(v)
+	setResiliencyDefaults(v)
+	setTracingDefaults(v)
+	setLoggingDefaults(v)
+	setEventBusDefaults(v)
+	setDocstoreDefaults(v)
+	setDBMSDefaults(v)
+	setInMemoryDefaults(v)
+	setAWSDefaults(v)
+	setAuthDefaults(v)
+	setSecretsDefaults(v)
+	setFeaturesDefaults(v)
 }
 
-// NewKernel Generate a global configuration from alexandria-config.yml file
-func NewKernel(ctx context.Context) (*Kernel, error) {
-	// Context is required to use gocloud.dev functions
-
-	kernelConfig := new(Kernel)
-
-	// Init config
-	viper.SetConfigName("alexandria-config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("./config/")
-	viper.AddConfigPath("/etc/alexandria/")
-	viper.AddConfigPath("$HOME/.alexandria")
-	viper.AddConfigPath(".")
-
-	// Open config
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Config file not found; ignore error if desired
-			_ = viper.SafeWriteConfig()
-		}
-
-		// Config file was found but another error was produced, use default values
+// newKernel maps the kernel configuration from the given viper instance
+func newKernel(v *viper.Viper) *Kernel {
+	return &Kernel{
+		Transport:  newTransportConfig(v),
+		Resiliency: newResiliencyConfig(v),
+		Tracing:    newTracingConfig(v),
+		Logging:    newLoggingConfig(v),
+		EventBus:   newEventBusConfig(v),
+		Docstore:   newDocstoreConfig(v),
+		DBMS:       newDBMSConfig(v),
+		InMemory:   newInMemoryConfig(v),
+		AWS:        newAWSConfig(v),
+		Auth:       newAuthConfig(v),
+		Secrets:    newSecretsConfig(v),
+		Features:   newFeaturesConfig(v),
+		Version:    v.GetString("alexandria.info.version"),
+		Service:    v.GetString("alexandria.info.service"),
+		Profile:    v.GetString("alexandria.info.profile"),
+		Sections:   make(map[string]interface{}),
 	}
+}
 
-	// Map kernel configuration
-	kernelConfig.Transport = newTransportConfig()
-	kernelConfig.Tracing = newTracingConfig()
-	kernelConfig.EventBus = newEventBusConfig()
-	kernelConfig.Docstore = newDocstoreConfig()
-	kernelConfig.DBMS = newDBMSConfig()
-	kernelConfig.InMemory = newInMemoryConfig()
-	kernelConfig.AWS = newAWSConfig()
-	kernelConfig.Auth = newAuthConfig()
-
-	kernelConfig.Version = viper.GetString("alexandria.info.version")
-	kernelConfig.Service = viper.GetString("alexandria.info.service")
-
-	// Prefer AWS KMS/Hashicorp Vault/Key Parameter Store over local, replace default or local config
-	// TODO: Implement Hashicorp Vault or AWS KMS key/secret fetching
+// NewDefaultKernel returns a Kernel holding default values only, useful for tests
+func NewDefaultKernel() *Kernel {
+	v := viper.New()
+	setKernelDefaults(v)
+	return newKernel(v)
+}
 
-	return kernelConfig, nil
+// NewKernel Generate a validated configuration from alexandria-config.yml file using default
+// Loader options
+func NewKernel(ctx context.Context) (*Kernel, error) {
+	return NewLoader().Load(ctx)
 }
@@ -2,25 +2,81 @@ package config
 
 import "github.com/spf13/viper"
 
-type docstore struct {
+// Docstore Document database settings
+type Docstore struct {
 	Collection   string
 	PartitionKey string
 	SortKey      string
 	AllowScan    bool
+	// RevisionField Document field holding the revision used by optimistic locking
+	RevisionField string
+	DynamoDB      DocstoreDynamoDB
 }
 
-func init() {
-	viper.SetDefault("alexandria.persistence.doc.collection", "ALEXANDRIA_COLLECTION")
-	viper.SetDefault("alexandria.persistence.doc.partition_key", "")
-	viper.SetDefault("alexandria.persistence.doc.sort_key", "")
-	viper.SetDefault("alexandria.persistence.doc.allow_scan", false)
+// DocstoreDynamoDB AWS DynamoDB settings
+type DocstoreDynamoDB struct {
+	Region string
+	// Endpoint DynamoDB endpoint, allows local stand-ins (e.g. DynamoDB Local)
+	Endpoint string
+	// Provision Create the table and its indexes if missing, meant for local development
+	Provision bool
+	// BillingMode PAY_PER_REQUEST or PROVISIONED
+	BillingMode   string
+	ReadCapacity  int64
+	WriteCapacity int64
+	// AttributeTypes Key attribute types (S, N or B), keys are strings by default
+	AttributeTypes map[string]string
+	// Indexes Global secondary indexes created along the table
+	Indexes []DocstoreIndex
+	// decodeErrors Index decoding failures reported by Kernel.Validate
+	decodeErrors []error
 }
 
-func newDocstoreConfig() docstore {
-	return docstore{
-		Collection:   viper.GetString("alexandria.persistence.doc.collection"),
-		PartitionKey: viper.GetString("alexandria.persistence.doc.partition_key"),
-		SortKey:      viper.GetString("alexandria.persistence.doc.sort_key"),
-		AllowScan:    viper.GetBool("alexandria.persistence.doc.allow_scan"),
+// DocstoreIndex DynamoDB global secondary index declared in the configuration
+type DocstoreIndex struct {
+	Name         string `mapstructure:"name"`
+	PartitionKey string `mapstructure:"partition_key"`
+	SortKey      string `mapstructure:"sort_key"`
+}
+
+func setDocstoreDefaults(v *viper.Viper) {
+	v.SetDefault("alexandria.persistence.doc.collection", "ALEXANDRIA_COLLECTION")
+	v.SetDefault("alexandria.persistence.doc.partition_key", "")
+	v.SetDefault("alexandria.persistence.doc.sort_key", "")
+	v.SetDefault("alexandria.persistence.doc.allow_scan", false)
+	v.SetDefault("alexandria.persistence.doc.revision_field", "DocstoreRevision")
+	v.SetDefault("alexandria.persistence.doc.dynamodb.region", "")
+	v.SetDefault("alexandria.persistence.doc.dynamodb.endpoint", "")
+	v.SetDefault("alexandria.persistence.doc.dynamodb.provision", false)
+	v.SetDefault("alexandria.persistence.doc.dynamodb.billing_mode", "PAY_PER_REQUEST")
+	v.SetDefault("alexandria.persistence.doc.dynamodb.read_capacity", 5)
+	v.SetDefault("alexandria.persistence.doc.dynamodb.write_capacity", 5)
+	v.SetDefault("alexandria.persistence.doc.dynamodb.attribute_types", map[string]string{})
+	v.SetDefault("alexandria.persistence.doc.dynamodb.indexes", []map[string]interface{}{})
+}
+
+func newDocstoreConfig(v *viper.Viper) Docstore {
+	cfg := Docstore{
+		Collection:    v.GetString("alexandria.persistence.doc.collection"),
+		PartitionKey:  v.GetString("alexandria.persistence.doc.partition_key"),
+		SortKey:       v.GetString("alexandria.persistence.doc.sort_key"),
+		AllowScan:     v.GetBool("alexandria.persistence.doc.allow_scan"),
+		RevisionField: v.GetString("alexandria.persistence.doc.revision_field"),
+		DynamoDB: DocstoreDynamoDB{
+			Region:         v.GetString("alexandria.persistence.doc.dynamodb.region"),
+			Endpoint:       v.GetString("alexandria.persistence.doc.dynamodb.endpoint"),
+			Provision:      v.GetBool("alexandria.persistence.doc.dynamodb.provision"),
+			BillingMode:    v.GetString("alexandria.persistence.doc.dynamodb.billing_mode"),
+			ReadCapacity:   v.GetInt64("alexandria.persistence.doc.dynamodb.read_capacity"),
+			WriteCapacity:  v.GetInt64("alexandria.persistence.doc.dynamodb.write_capacity"),
+			AttributeTypes: v.GetStringMapString("alexandria.persistence.doc.dynamodb.attribute_types"),
+			Indexes:        make([]DocstoreIndex, 0),
+		},
+	}
+	if err := v.UnmarshalKey("alexandria.persistence.doc.dynamodb.indexes", &cfg.DynamoDB.Indexes); err != nil {
+		cfg.DynamoDB.decodeErrors = append(cfg.DynamoDB.decodeErrors,
+			decodeError("alexandria.persistence.doc.dynamodb.indexes", err))
 	}
+
+	return cfg
 }
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RedactedValue Replaces sensitive values in configuration dumps
+const RedactedValue = "REDACTED"
+
+// sensitiveKeys Key suffixes holding sensitive values
+var sensitiveKeys = []string{"password", "secret", "token"}
+
+// Dump writes the effective configuration (defaults, config files and OS env overrides) as YAML,
+// sensitive values and URL credentials are redacted. Secret references are not resolved
+func (l *Loader) Dump(w io.Writer) error {
+	v, err := l.read()
+	if err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(redactSettings("", v.AllSettings()))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+	return err
+}
+
+// redactSettings returns a copy of the given settings with sensitive values redacted
+func redactSettings(key string, value interface{}) interface{} {
+	switch val := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			redacted[k] = redactSettings(k, v)
+		}
+		return redacted
+	case map[interface{}]interface{}:
+		redacted := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			ks, _ := k.(string)
+			redacted[ks] = redactSettings(ks, v)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, 0, len(val))
+		for _, v := range val {
+			redacted = append(redacted, redactSettings(key, v))
+		}
+		return redacted
+	case string:
+		return redactValue(key, val)
+	default:
+		return value
+	}
+}
+
+func redactValue(key, value string) string {
+	// Secret references are not sensitive
+	if value == "" || strings.HasPrefix(value, SecretScheme) {
+		return value
+	}
+
+	for _, suffix := range sensitiveKeys {
+		if strings.HasSuffix(strings.ToLower(key), suffix) {
+			return RedactedValue
+		}
+	}
+
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), RedactedValue)
+			return u.String()
+		}
+	}
+
+	return value
+}
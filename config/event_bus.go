@@ -2,36 +2,103 @@ package config
 
 import (
 	"github.com/spf13/viper"
-	"os"
+	"time"
 )
 
-type eventBus struct {
+// EventBus Event bus (Apache Kafka) settings
+type EventBus struct {
 	KafkaBrokers []string
+	// KafkaClientID Client identifier sent to the brokers on every request
+	KafkaClientID string
+	// KafkaVersion Brokers' Apache Kafka version (e.g. 2.4.0)
+	KafkaVersion  string
+	KafkaSASL     KafkaSASL
+	KafkaTLS      KafkaTLS
+	KafkaConsumer KafkaConsumer
+	// KafkaTopics Topics to be created by the Kafka administrator
+	KafkaTopics []KafkaTopic
+	// decodeErrors Topic decoding failures reported by Kernel.Validate
+	decodeErrors []error
 }
 
-func init() {
-	viper.SetDefault("alexandria.eventbus.kafka.brokers", []string{"0.0.0.0:9092"})
+// KafkaSASL Apache Kafka SASL authentication settings
+type KafkaSASL struct {
+	Enabled bool
+	// Mechanism PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512
+	Mechanism string
+	User      string
+	Password  string
 }
 
-func newEventBusConfig() eventBus {
-	cfg := eventBus{
-		KafkaBrokers: viper.GetStringSlice("alexandria.eventbus.kafka.brokers"),
-	}
+// KafkaTLS Apache Kafka TLS settings
+type KafkaTLS struct {
+	Enabled            bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
 
-	// Start up required kafka env
-	_ = os.Setenv("KAFKA_BROKERS", getKafkaBrokerString(cfg.KafkaBrokers))
+// KafkaConsumer Apache Kafka consumer group settings
+type KafkaConsumer struct {
+	// InitialOffset Offset used by new consumer groups (newest or oldest)
+	InitialOffset  string
+	SessionTimeout time.Duration
+}
 
-	return cfg
+// KafkaTopic Apache Kafka topic created by the Kafka administrator
+type KafkaTopic struct {
+	Name              string        `mapstructure:"name"`
+	Partitions        int32         `mapstructure:"partitions"`
+	ReplicationFactor int16         `mapstructure:"replication_factor"`
+	Retention         time.Duration `mapstructure:"retention"`
 }
 
-func getKafkaBrokerString(brokers []string) string {
-	brokerStr := ""
-	for i, broker := range brokers {
-		brokerStr += broker
-		if len(brokers) > i+1 {
-			brokerStr += ","
-		}
+func setEventBusDefaults(v *viper.Viper) {
+	v.SetDefault("alexandria.eventbus.kafka.brokers", []string{"0.0.0.0:9092"})
+	v.SetDefault("alexandria.eventbus.kafka.client_id", "alexandria")
+	v.SetDefault("alexandria.eventbus.kafka.version", "2.4.0")
+	v.SetDefault("alexandria.eventbus.kafka.sasl.enabled", false)
+	v.SetDefault("alexandria.eventbus.kafka.sasl.mechanism", "PLAIN")
+	v.SetDefault("alexandria.eventbus.kafka.sasl.user", "")
+	v.SetDefault("alexandria.eventbus.kafka.sasl.password", "")
+	v.SetDefault("alexandria.eventbus.kafka.tls.enabled", false)
+	v.SetDefault("alexandria.eventbus.kafka.tls.ca_file", "")
+	v.SetDefault("alexandria.eventbus.kafka.tls.cert_file", "")
+	v.SetDefault("alexandria.eventbus.kafka.tls.key_file", "")
+	v.SetDefault("alexandria.eventbus.kafka.tls.insecure_skip_verify", false)
+	v.SetDefault("alexandria.eventbus.kafka.consumer.initial_offset", "newest")
+	v.SetDefault("alexandria.eventbus.kafka.consumer.session_timeout", 10*time.Second)
+	v.SetDefault("alexandria.eventbus.kafka.topics", []map[string]interface{}{})
+}
+
+func newEventBusConfig(v *viper.Viper) EventBus {
+	cfg := EventBus{
+		KafkaBrokers:  getAddresses(v, "alexandria.eventbus.kafka.brokers"),
+		KafkaClientID: v.GetString("alexandria.eventbus.kafka.client_id"),
+		KafkaVersion:  v.GetString("alexandria.eventbus.kafka.version"),
+		KafkaSASL: KafkaSASL{
+			Enabled:   v.GetBool("alexandria.eventbus.kafka.sasl.enabled"),
+			Mechanism: v.GetString("alexandria.eventbus.kafka.sasl.mechanism"),
+			User:      v.GetString("alexandria.eventbus.kafka.sasl.user"),
+			Password:  v.GetString("alexandria.eventbus.kafka.sasl.password"),
+		},
+		KafkaTLS: KafkaTLS{
+			Enabled:            v.GetBool("alexandria.eventbus.kafka.tls.enabled"),
+			CAFile:             v.GetString("alexandria.eventbus.kafka.tls.ca_file"),
+			CertFile:           v.GetString("alexandria.eventbus.kafka.tls.cert_file"),
+			KeyFile:            v.GetString("alexandria.eventbus.kafka.tls.key_file"),
+			InsecureSkipVerify: v.GetBool("alexandria.eventbus.kafka.tls.insecure_skip_verify"),
+		},
+		KafkaConsumer: KafkaConsumer{
+			InitialOffset:  v.GetString("alexandria.eventbus.kafka.consumer.initial_offset"),
+			SessionTimeout: v.GetDuration("alexandria.eventbus.kafka.consumer.session_timeout"),
+		},
+		KafkaTopics: make([]KafkaTopic, 0),
+	}
+	if err := v.UnmarshalKey("alexandria.eventbus.kafka.topics", &cfg.KafkaTopics); err != nil {
+		cfg.decodeErrors = append(cfg.decodeErrors, decodeError("alexandria.eventbus.kafka.topics", err))
 	}
 
-	return brokerStr
+	return cfg
 }
@@ -2,7 +2,6 @@ package config
 
 import (
 	"context"
-	"errors"
 	"os"
 	"testing"
 )
@@ -15,10 +14,12 @@ func TestNewKernel(t *testing.T) {
 
 	t.Logf("%+v", cfg)
 
-	// Verify kafka broker env variable
-	kafkaBrokers := os.Getenv("KAFKA_BROKERS")
-	if kafkaBrokers == "" {
-		t.Error(errors.New("no kafka brokers found"))
+	// Broker configuration must not leak into the process environment
+	if len(cfg.EventBus.KafkaBrokers) == 0 {
+		t.Error("no kafka brokers found")
 	}
-	t.Log(kafkaBrokers)
+	if kafkaBrokers := os.Getenv("KAFKA_BROKERS"); kafkaBrokers != "" {
+		t.Errorf("kafka brokers env variable was set: %s", kafkaBrokers)
+	}
+	t.Log(cfg.EventBus.KafkaBrokers)
 }
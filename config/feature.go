@@ -0,0 +1,47 @@
+package config
+
+import "github.com/spf13/viper"
+
+// Features Feature flags settings
+type Features struct {
+	// Store Feature flag store (config, file or redis)
+	Store string
+	// File JSON flags file used by the file store
+	File string
+	// RedisKey Redis hash holding flags used by the redis store
+	RedisKey string
+	// Flags Feature flags used by the config store
+	Flags []FeatureFlag
+	// decodeErrors Flag decoding failures reported by Kernel.Validate
+	decodeErrors []error
+}
+
+// FeatureFlag Feature flag declared in the configuration
+type FeatureFlag struct {
+	Name       string   `mapstructure:"name"`
+	Enabled    bool     `mapstructure:"enabled"`
+	Percentage *int     `mapstructure:"percentage"`
+	Users      []string `mapstructure:"users"`
+	Roles      []string `mapstructure:"roles"`
+}
+
+func setFeaturesDefaults(v *viper.Viper) {
+	v.SetDefault("alexandria.features.store", "config")
+	v.SetDefault("alexandria.features.file", "/etc/alexandria/flags.json")
+	v.SetDefault("alexandria.features.redis_key", "alexandria:features")
+	v.SetDefault("alexandria.features.flags", []map[string]interface{}{})
+}
+
+func newFeaturesConfig(v *viper.Viper) Features {
+	cfg := Features{
+		Store:    v.GetString("alexandria.features.store"),
+		File:     v.GetString("alexandria.features.file"),
+		RedisKey: v.GetString("alexandria.features.redis_key"),
+		Flags:    make([]FeatureFlag, 0),
+	}
+	if err := v.UnmarshalKey("alexandria.features.flags", &cfg.Flags); err != nil {
+		cfg.decodeErrors = append(cfg.decodeErrors, decodeError("alexandria.features.flags", err))
+	}
+
+	return cfg
+}
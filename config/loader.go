@@ -0,0 +1,218 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Loader Loads a Kernel configuration using its own viper instance, allows multiple kernels
+// in a single process
+type Loader struct {
+	configName  string
+	configType  string
+	searchPaths []string
+	envPrefix   string
+	profile     string
+	configFile  string
+	configBytes []byte
+	sections    []Section
+	// secretProviders Providers registered using WithSecretProvider
+	secretProviders map[string]SecretProvider
+}
+
+// LoaderOption Loader functional option
+type LoaderOption func(*Loader)
+
+// WithConfigName sets the config file name (without extension) to look for in the search paths,
+// default is alexandria-config
+func WithConfigName(name string) LoaderOption {
+	return func(l *Loader) {
+		l.configName = name
+	}
+}
+
+// WithConfigType sets the config format (yaml, json, toml...), default is yaml
+func WithConfigType(configType string) LoaderOption {
+	return func(l *Loader) {
+		l.configType = configType
+	}
+}
+
+// WithSearchPaths replaces the default config file search paths
+// (./config/, /etc/alexandria/, $HOME/.alexandria and .)
+func WithSearchPaths(paths ...string) LoaderOption {
+	return func(l *Loader) {
+		l.searchPaths = paths
+	}
+}
+
+// WithEnvPrefix prepends the given prefix to OS env variable overrides,
+// e.g. PREFIX_ALEXANDRIA_PERSISTENCE_DBMS_URL overrides alexandria.persistence.dbms.url
+func WithEnvPrefix(prefix string) LoaderOption {
+	return func(l *Loader) {
+		l.envPrefix = prefix
+	}
+}
+
+// WithProfile sets the deployment profile (dev, staging or prod) whose config file is layered
+// over the base config, default is alexandria.info.profile
+func WithProfile(profile string) LoaderOption {
+	return func(l *Loader) {
+		l.profile = profile
+	}
+}
+
+// WithConfigFile reads the configuration from the given file path, search paths are ignored
+func WithConfigFile(path string) LoaderOption {
+	return func(l *Loader) {
+		l.configFile = path
+	}
+}
+
+// WithConfigBytes reads the configuration from memory using the loader's config type,
+// search paths and config file are ignored
+func WithConfigBytes(data []byte) LoaderOption {
+	return func(l *Loader) {
+		l.configBytes = data
+	}
+}
+
+// NewLoader returns a configuration loader, default options look for alexandria-config.yaml in
+// ./config/, /etc/alexandria/, $HOME/.alexandria and the working directory.
+//
+// Every value might be overridden by an OS env variable (e.g. ALEXANDRIA_PERSISTENCE_DBMS_URL) and
+// the profile's config file (e.g. alexandria-config.prod.yaml) is layered over the base config file
+func NewLoader(opts ...LoaderOption) *Loader {
+	l := &Loader{
+		configName:  "alexandria-config",
+		configType:  "yaml",
+		searchPaths: []string{"./config/", "/etc/alexandria/", "$HOME/.alexandria", "."},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Load reads, maps and validates a new Kernel configuration, returns a *ValidationError listing
+// every problem if the configuration is not valid
+func (l *Loader) Load(ctx context.Context) (*Kernel, error) {
+	kernel, _, err := l.load(ctx)
+	return kernel, err
+}
+
+// load reads, maps and validates a new Kernel configuration, returns the resolved viper instance
+// along with the kernel
+func (l *Loader) load(ctx context.Context) (*Kernel, *viper.Viper, error) {
+	// Context is required to use gocloud.dev functions
+	v, err := l.read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Prefer Hashicorp Vault/AWS Parameter Store/AWS Secrets Manager/AWS KMS/mounted secrets over
+	// local config, replace every secret reference (secret://provider/path#key)
+	if err := resolveSecrets(ctx, v, newSecretsConfig(v), l.secretProviders); err != nil {
+		return nil, nil, err
+	}
+
+	kernel := newKernel(v)
+	if kernel.Sections, err = newSections(v, l.sections); err != nil {
+		return nil, nil, err
+	}
+	if err := kernel.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return kernel, v, nil
+}
+
+// read returns a new viper instance with the loader's configuration sources
+func (l *Loader) read() (*viper.Viper, error) {
+	v := viper.New()
+	setKernelDefaults(v)
+	setSectionDefaults(v, l.sections)
+	v.SetConfigType(l.configType)
+
+	// alexandria.persistence.dbms.url is overridden by ALEXANDRIA_PERSISTENCE_DBMS_URL
+	v.SetEnvPrefix(l.envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	switch {
+	case l.configBytes != nil:
+		if err := v.ReadConfig(bytes.NewReader(l.configBytes)); err != nil {
+			return nil, fmt.Errorf("read config: %w", err)
+		}
+	case l.configFile != "":
+		v.SetConfigFile(l.configFile)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("read config %s: %w", l.configFile, err)
+		}
+	default:
+		v.SetConfigName(l.configName)
+		for _, path := range l.searchPaths {
+			v.AddConfigPath(path)
+		}
+
+		if err := v.ReadInConfig(); err != nil {
+			// Use default values if config file was not found, parse errors must not be ignored
+			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+				return nil, fmt.Errorf("read config %s: %w", v.ConfigFileUsed(), err)
+			}
+		}
+	}
+
+	if err := l.mergeProfile(v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// mergeProfile layers the profile's config file over the base config, profile files are optional
+func (l *Loader) mergeProfile(v *viper.Viper) error {
+	// Explicit profiles take precedence over alexandria.info.profile
+	if l.profile != "" {
+		v.Set("alexandria.info.profile", l.profile)
+	}
+	profile := v.GetString("alexandria.info.profile")
+	if profile == "" || l.configBytes != nil {
+		return nil
+	}
+
+	pv := viper.New()
+	pv.SetConfigType(l.configType)
+	if l.configFile != "" {
+		ext := filepath.Ext(l.configFile)
+		file := strings.TrimSuffix(l.configFile, ext) + "." + profile + ext
+		if _, err := os.Stat(file); err != nil {
+			return nil
+		}
+		pv.SetConfigFile(file)
+	} else {
+		pv.SetConfigName(l.configName + "." + profile)
+		for _, path := range l.searchPaths {
+			pv.AddConfigPath(path)
+		}
+	}
+
+	if err := pv.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			return nil
+		}
+		return fmt.Errorf("read profile config %s: %w", pv.ConfigFileUsed(), err)
+	}
+	if err := v.MergeConfigMap(pv.AllSettings()); err != nil {
+		return fmt.Errorf("merge profile config %s: %w", pv.ConfigFileUsed(), err)
+	}
+
+	return nil
+}
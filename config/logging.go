@@ -0,0 +1,19 @@
+package config
+
+import "github.com/spf13/viper"
+
+// Logging Logging settings
+type Logging struct {
+	// Level Minimum log level (debug, info, warn or error), might be updated at runtime
+	Level string
+}
+
+func setLoggingDefaults(v *viper.Viper) {
+	v.SetDefault("alexandria.log.level", "info")
+}
+
+func newLoggingConfig(v *viper.Viper) Logging {
+	return Logging{
+		Level: v.GetString("alexandria.log.level"),
+	}
+}
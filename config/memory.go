@@ -1,29 +1,120 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"strings"
+	"time"
 
-type inMemory struct {
+	"github.com/spf13/viper"
+)
+
+// InMemory In-memory database (Redis) settings
+type InMemory struct {
 	Network  string
 	Host     string
 	Port     int
 	Password string
 	Database string
+
+	Sentinel InMemorySentinel
+	Cluster  InMemoryCluster
+	TLS      InMemoryTLS
+	Connect  InMemoryConnect
+}
+
+// InMemorySentinel Redis Sentinel settings, used instead of Host and Port if MasterName is set
+type InMemorySentinel struct {
+	MasterName string
+	// Addresses Sentinel nodes (host:port)
+	Addresses []string
+	Password  string
+}
+
+// InMemoryCluster Redis Cluster settings, used instead of Host and Port if Addresses are set
+type InMemoryCluster struct {
+	// Addresses Cluster seed nodes (host:port)
+	Addresses []string
+}
+
+// InMemoryTLS Redis TLS settings
+type InMemoryTLS struct {
+	Enabled            bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// InMemoryConnect Redis startup probing settings
+type InMemoryConnect struct {
+	// Retries Ping attempts after the first failure
+	Retries int
+	// Backoff Delay before the first retry, doubled on every attempt
+	Backoff time.Duration
+	// Timeout Dial timeout
+	Timeout time.Duration
 }
 
-func init() {
-	viper.SetDefault("alexandria.persistence.mem.network", "")
-	viper.SetDefault("alexandria.persistence.mem.host", "0.0.0.0")
-	viper.SetDefault("alexandria.persistence.mem.port", 6379)
-	viper.SetDefault("alexandria.persistence.mem.password", "")
-	viper.SetDefault("alexandria.persistence.mem.database", "0")
+func setInMemoryDefaults(v *viper.Viper) {
+	v.SetDefault("alexandria.persistence.mem.network", "")
+	v.SetDefault("alexandria.persistence.mem.host", "0.0.0.0")
+	v.SetDefault("alexandria.persistence.mem.port", 6379)
+	v.SetDefault("alexandria.persistence.mem.password", "")
+	v.SetDefault("alexandria.persistence.mem.database", "0")
+	v.SetDefault("alexandria.persistence.mem.sentinel.master_name", "")
+	v.SetDefault("alexandria.persistence.mem.sentinel.addresses", []string{})
+	v.SetDefault("alexandria.persistence.mem.sentinel.password", "")
+	v.SetDefault("alexandria.persistence.mem.cluster.addresses", []string{})
+	v.SetDefault("alexandria.persistence.mem.tls.enabled", false)
+	v.SetDefault("alexandria.persistence.mem.tls.ca_file", "")
+	v.SetDefault("alexandria.persistence.mem.tls.cert_file", "")
+	v.SetDefault("alexandria.persistence.mem.tls.key_file", "")
+	v.SetDefault("alexandria.persistence.mem.tls.insecure_skip_verify", false)
+	v.SetDefault("alexandria.persistence.mem.connect.retries", 5)
+	v.SetDefault("alexandria.persistence.mem.connect.backoff", 500*time.Millisecond)
+	v.SetDefault("alexandria.persistence.mem.connect.timeout", 5*time.Second)
 }
 
-func newInMemoryConfig() inMemory {
-	return inMemory{
-		Host:     viper.GetString("alexandria.persistence.mem.host"),
-		Port:     viper.GetInt("alexandria.persistence.mem.port"),
-		Password: viper.GetString("alexandria.persistence.mem.password"),
-		Network:  viper.GetString("alexandria.persistence.mem.network"),
-		Database: viper.GetString("alexandria.persistence.mem.database"),
+func newInMemoryConfig(v *viper.Viper) InMemory {
+	return InMemory{
+		Host:     v.GetString("alexandria.persistence.mem.host"),
+		Port:     v.GetInt("alexandria.persistence.mem.port"),
+		Password: v.GetString("alexandria.persistence.mem.password"),
+		Network:  v.GetString("alexandria.persistence.mem.network"),
+		Database: v.GetString("alexandria.persistence.mem.database"),
+		Sentinel: InMemorySentinel{
+			MasterName: v.GetString("alexandria.persistence.mem.sentinel.master_name"),
+			Addresses:  getAddresses(v, "alexandria.persistence.mem.sentinel.addresses"),
+			Password:   v.GetString("alexandria.persistence.mem.sentinel.password"),
+		},
+		Cluster: InMemoryCluster{
+			Addresses: getAddresses(v, "alexandria.persistence.mem.cluster.addresses"),
+		},
+		TLS: InMemoryTLS{
+			Enabled:            v.GetBool("alexandria.persistence.mem.tls.enabled"),
+			CAFile:             v.GetString("alexandria.persistence.mem.tls.ca_file"),
+			CertFile:           v.GetString("alexandria.persistence.mem.tls.cert_file"),
+			KeyFile:            v.GetString("alexandria.persistence.mem.tls.key_file"),
+			InsecureSkipVerify: v.GetBool("alexandria.persistence.mem.tls.insecure_skip_verify"),
+		},
+		Connect: InMemoryConnect{
+			Retries: v.GetInt("alexandria.persistence.mem.connect.retries"),
+			Backoff: v.GetDuration("alexandria.persistence.mem.connect.backoff"),
+			Timeout: v.GetDuration("alexandria.persistence.mem.connect.timeout"),
+		},
 	}
 }
+
+// getAddresses returns a list of addresses, OS env overrides use comma-separated values (e.g.
+// ALEXANDRIA_PERSISTENCE_MEM_CLUSTER_ADDRESSES=redis:7000,redis_1:7000)
+func getAddresses(v *viper.Viper, key string) []string {
+	addresses := make([]string, 0)
+	for _, address := range v.GetStringSlice(key) {
+		for _, a := range strings.Split(address, ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				addresses = append(addresses, a)
+			}
+		}
+	}
+
+	return addresses
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// Resiliency Resiliency patterns settings
+type Resiliency struct {
+	// RateLimit Requests per second allowed by each resilient endpoint, might be updated at runtime
+	RateLimit float64
+	// RateBurst Maximum requests allowed at once by each resilient endpoint
+	RateBurst int
+	Limiter   ResiliencyLimiter
+	// Policy Default policy of every resilient endpoint
+	Policy ResiliencyPolicy
+	// Policies Per service/action policies, unset keys are taken from Policy
+	Policies []ResiliencyPolicy
+	// decodeErrors Policy decoding failures reported by Kernel.Validate
+	decodeErrors []error
+}
+
+// ResiliencyPolicy Fault-tolerance settings of a service/action endpoint
+type ResiliencyPolicy struct {
+	Service string `mapstructure:"service"`
+	// Action Endpoint name, empty or * applies the policy to every service's endpoint
+	Action string `mapstructure:"action"`
+	// Timeout Endpoint deadline, 0 disables it
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Idempotent Only idempotent endpoints are retried
+	Idempotent bool `mapstructure:"idempotent"`
+	Retries    int  `mapstructure:"retries"`
+	// RetryBackoff Base retry backoff, doubled on every attempt and jittered
+	RetryBackoff    time.Duration `mapstructure:"retry_backoff"`
+	RetryMaxBackoff time.Duration `mapstructure:"retry_max_backoff"`
+	// MaxConcurrent Concurrent requests allowed by the bulkhead, 0 disables it
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+	// BreakerMaxRequests Requests allowed while the circuit breaker is half-open
+	BreakerMaxRequests uint32 `mapstructure:"breaker_max_requests"`
+	// BreakerInterval Closed state cyclic period to clear failure counts, 0 never clears them
+	BreakerInterval time.Duration `mapstructure:"breaker_interval"`
+	// BreakerTimeout Open state period before going half-open
+	BreakerTimeout time.Duration `mapstructure:"breaker_timeout"`
+	// BreakerFailures Consecutive failures tripping the circuit breaker
+	BreakerFailures uint32 `mapstructure:"breaker_failures"`
+}
+
+// PolicyFor returns the policy of the given service/action, falling back to service-wide policies and
+// then to the default policy
+func (r Resiliency) PolicyFor(service, action string) ResiliencyPolicy {
+	policy := r.Policy
+	for _, p := range r.Policies {
+		if !strings.EqualFold(p.Service, service) {
+			continue
+		}
+		if strings.EqualFold(p.Action, action) {
+			policy = p
+			break
+		}
+		if p.Action == "" || p.Action == "*" {
+			policy = p
+		}
+	}
+
+	policy.Service = service
+	policy.Action = action
+	return policy
+}
+
+// ResiliencyLimiter Rate limiter settings, limits are taken from RateLimit and RateBurst
+type ResiliencyLimiter struct {
+	// Store memory enforces limits per replica, redis enforces them cluster-wide
+	Store string
+	// Algorithm token_bucket or sliding_window
+	Algorithm string
+	// Window Sliding window length, RateLimit * Window requests are allowed per window
+	Window time.Duration
+	// Prefix Redis keys prefix
+	Prefix string
+	// TrustedProxies CIDRs or IPs of the reverse proxies whose X-Forwarded-For headers are trusted
+	TrustedProxies []string
+}
+
+func setResiliencyDefaults(v *viper.Viper) {
+	v.SetDefault("alexandria.service.resiliency.rate_limit", 1.0)
+	v.SetDefault("alexandria.service.resiliency.rate_burst", 1)
+	v.SetDefault("alexandria.service.resiliency.limiter.store", "memory")
+	v.SetDefault("alexandria.service.resiliency.limiter.algorithm", "token_bucket")
+	v.SetDefault("alexandria.service.resiliency.limiter.window", time.Minute)
+	v.SetDefault("alexandria.service.resiliency.limiter.prefix", "ratelimit:")
+	v.SetDefault("alexandria.service.resiliency.limiter.trusted_proxies", []string{})
+	v.SetDefault("alexandria.service.resiliency.policy.timeout", time.Duration(0))
+	v.SetDefault("alexandria.service.resiliency.policy.idempotent", false)
+	v.SetDefault("alexandria.service.resiliency.policy.retries", 2)
+	v.SetDefault("alexandria.service.resiliency.policy.retry_backoff", 100*time.Millisecond)
+	v.SetDefault("alexandria.service.resiliency.policy.retry_max_backoff", 2*time.Second)
+	v.SetDefault("alexandria.service.resiliency.policy.max_concurrent", 0)
+	v.SetDefault("alexandria.service.resiliency.policy.breaker_max_requests", 100)
+	v.SetDefault("alexandria.service.resiliency.policy.breaker_interval", time.Duration(0))
+	v.SetDefault("alexandria.service.resiliency.policy.breaker_timeout", 15*time.Second)
+	v.SetDefault("alexandria.service.resiliency.policy.breaker_failures", 5)
+	v.SetDefault("alexandria.service.resiliency.policies", []map[string]interface{}{})
+}
+
+func newResiliencyConfig(v *viper.Viper) Resiliency {
+	cfg := Resiliency{
+		RateLimit: v.GetFloat64("alexandria.service.resiliency.rate_limit"),
+		RateBurst: v.GetInt("alexandria.service.resiliency.rate_burst"),
+		Limiter: ResiliencyLimiter{
+			Store:          v.GetString("alexandria.service.resiliency.limiter.store"),
+			Algorithm:      v.GetString("alexandria.service.resiliency.limiter.algorithm"),
+			Window:         v.GetDuration("alexandria.service.resiliency.limiter.window"),
+			Prefix:         v.GetString("alexandria.service.resiliency.limiter.prefix"),
+			TrustedProxies: getAddresses(v, "alexandria.service.resiliency.limiter.trusted_proxies"),
+		},
+		Policy: ResiliencyPolicy{
+			Timeout:            v.GetDuration("alexandria.service.resiliency.policy.timeout"),
+			Idempotent:         v.GetBool("alexandria.service.resiliency.policy.idempotent"),
+			Retries:            v.GetInt("alexandria.service.resiliency.policy.retries"),
+			RetryBackoff:       v.GetDuration("alexandria.service.resiliency.policy.retry_backoff"),
+			RetryMaxBackoff:    v.GetDuration("alexandria.service.resiliency.policy.retry_max_backoff"),
+			MaxConcurrent:      v.GetInt("alexandria.service.resiliency.policy.max_concurrent"),
+			BreakerMaxRequests: v.GetUint32("alexandria.service.resiliency.policy.breaker_max_requests"),
+			BreakerInterval:    v.GetDuration("alexandria.service.resiliency.policy.breaker_interval"),
+			BreakerTimeout:     v.GetDuration("alexandria.service.resiliency.policy.breaker_timeout"),
+			BreakerFailures:    v.GetUint32("alexandria.service.resiliency.policy.breaker_failures"),
+		},
+		Policies: make([]ResiliencyPolicy, 0),
+	}
+
+	// Policies are decoded on top of the default policy to inherit unset keys
+	var policies []map[string]interface{}
+	if err := v.UnmarshalKey("alexandria.service.resiliency.policies", &policies); err != nil {
+		cfg.decodeErrors = append(cfg.decodeErrors, decodeError("alexandria.service.resiliency.policies", err))
+	}
+	for i, raw := range policies {
+		policy := cfg.Policy
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           &policy,
+		})
+		if err == nil {
+			err = decoder.Decode(raw)
+		}
+		if err != nil {
+			cfg.decodeErrors = append(cfg.decodeErrors,
+				decodeError(fmt.Sprintf("alexandria.service.resiliency.policies[%d]", i), err))
+			continue
+		}
+		cfg.Policies = append(cfg.Policies, policy)
+	}
+
+	return cfg
+}
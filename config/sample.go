@@ -0,0 +1,347 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// descriptions Documents kernel keys in generated samples and schemas
+var descriptions = map[string]string{
+	"alexandria.info.service":                                   "Service name",
+	"alexandria.info.version":                                   "Service version",
+	"alexandria.info.profile":                                   "Deployment profile (dev, staging or prod), example values are rejected in prod",
+	"alexandria.service.transport.http.host":                    "HTTP server host",
+	"alexandria.service.transport.http.port":                    "HTTP server port",
+	"alexandria.service.transport.rpc.host":                     "gRPC server host",
+	"alexandria.service.transport.rpc.port":                     "gRPC server port",
+	"alexandria.service.resiliency.rate_limit":                  "Requests per second allowed by each resilient endpoint, reloaded at runtime",
+	"alexandria.service.resiliency.rate_burst":                  "Maximum requests allowed at once by each resilient endpoint",
+	"alexandria.service.resiliency.limiter.store":               "Rate limiter store, memory enforces limits per replica and redis cluster-wide",
+	"alexandria.service.resiliency.limiter.algorithm":           "Rate limiting algorithm (token_bucket or sliding_window)",
+	"alexandria.service.resiliency.limiter.window":              "Sliding window length, rate_limit * window requests are allowed per window",
+	"alexandria.service.resiliency.limiter.prefix":              "Redis rate limiter keys prefix",
+	"alexandria.service.resiliency.limiter.trusted_proxies":     "Reverse proxy CIDRs or IPs whose X-Forwarded-For headers are trusted",
+	"alexandria.service.resiliency.policy":                      "Default resiliency policy of every endpoint",
+	"alexandria.service.resiliency.policy.timeout":              "Endpoint timeout, 0 disables it",
+	"alexandria.service.resiliency.policy.idempotent":           "Retries are only made by idempotent endpoints",
+	"alexandria.service.resiliency.policy.retries":              "Retries made after a failed request",
+	"alexandria.service.resiliency.policy.retry_backoff":        "Base retry backoff, doubled on every attempt and jittered",
+	"alexandria.service.resiliency.policy.retry_max_backoff":    "Maximum retry backoff",
+	"alexandria.service.resiliency.policy.max_concurrent":       "Concurrent requests allowed by the bulkhead, 0 disables it",
+	"alexandria.service.resiliency.policy.breaker_max_requests": "Requests allowed while the circuit breaker is half-open",
+	"alexandria.service.resiliency.policy.breaker_interval":     "Period clearing circuit breaker failure counts, 0 never clears them",
+	"alexandria.service.resiliency.policy.breaker_timeout":      "Open circuit breaker period before going half-open",
+	"alexandria.service.resiliency.policy.breaker_failures":     "Consecutive failures tripping the circuit breaker",
+	"alexandria.service.resiliency.policies":                    "Per service/action resiliency policies, unset keys are taken from policy",
+	"alexandria.log.level":                                      "Minimum log level (debug, info, warn or error), reloaded at runtime",
+	"alexandria.tracing.zipkin.host":                            "Zipkin spans collector URL",
+	"alexandria.tracing.zipkin.endpoint":                        "Service endpoint reported to Zipkin",
+	"alexandria.tracing.zipkin.bridge":                          "Bridge OpenTracing spans to Zipkin",
+	"alexandria.eventbus.kafka.brokers":                         "Apache Kafka broker nodes (host:port)",
+	"alexandria.eventbus.kafka.client_id":                       "Client identifier sent to the brokers",
+	"alexandria.eventbus.kafka.version":                         "Brokers' Apache Kafka version",
+	"alexandria.eventbus.kafka.sasl.enabled":                    "Enable SASL authentication",
+	"alexandria.eventbus.kafka.sasl.mechanism":                  "SASL mechanism (PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512)",
+	"alexandria.eventbus.kafka.sasl.user":                       "SASL user",
+	"alexandria.eventbus.kafka.sasl.password":                   "SASL password",
+	"alexandria.eventbus.kafka.tls.enabled":                     "Enable TLS connections",
+	"alexandria.eventbus.kafka.tls.ca_file":                     "PEM-encoded certificate authorities file",
+	"alexandria.eventbus.kafka.tls.cert_file":                   "PEM-encoded client certificate file",
+	"alexandria.eventbus.kafka.tls.key_file":                    "PEM-encoded client key file",
+	"alexandria.eventbus.kafka.tls.insecure_skip_verify":        "Skip broker certificate verification",
+	"alexandria.eventbus.kafka.consumer.initial_offset":         "Offset used by new consumer groups (newest or oldest)",
+	"alexandria.eventbus.kafka.consumer.session_timeout":        "Consumer group session timeout",
+	"alexandria.eventbus.kafka.topics":                          "Topics created by the Kafka administrator",
+	"alexandria.persistence.dbms.url":                           "Relational database connection URL",
+	"alexandria.persistence.dbms.driver":                        "Relational database driver",
+	"alexandria.persistence.dbms.user":                          "Relational database user",
+	"alexandria.persistence.dbms.password":                      "Relational database password",
+	"alexandria.persistence.dbms.host":                          "Relational database host",
+	"alexandria.persistence.dbms.port":                          "Relational database port",
+	"alexandria.persistence.dbms.database":                      "Relational database name",
+	"alexandria.persistence.dbms.pool.max_open_conns":           "Maximum open connections",
+	"alexandria.persistence.dbms.pool.max_idle_conns":           "Maximum idle connections",
+	"alexandria.persistence.dbms.pool.conn_max_lifetime":        "Maximum amount of time a connection might be reused",
+	"alexandria.persistence.dbms.tls.mode":                      "SSL mode (disable, require, verify-ca or verify-full), applied to URLs without sslmode",
+	"alexandria.persistence.dbms.tls.root_cert":                 "PEM-encoded certificate authorities file",
+	"alexandria.persistence.dbms.tls.cert":                      "PEM-encoded client certificate file",
+	"alexandria.persistence.dbms.tls.key":                       "PEM-encoded client key file",
+	"alexandria.persistence.dbms.connect.retries":               "Startup ping attempts after the first failure",
+	"alexandria.persistence.dbms.connect.backoff":               "Initial backoff between startup pings, doubled on every attempt",
+	"alexandria.persistence.dbms.connect.timeout":               "Timeout of every startup ping",
+	"alexandria.persistence.dbms.migrations.dir":                "Schema migrations directory, pending migrations are applied on startup if set",
+	"alexandria.persistence.dbms.migrations.table":              "Table tracking applied schema migrations",
+	"alexandria.persistence.mem.network":                        "Redis network (tcp or unix)",
+	"alexandria.persistence.mem.host":                           "Redis host",
+	"alexandria.persistence.mem.port":                           "Redis port",
+	"alexandria.persistence.mem.password":                       "Redis password",
+	"alexandria.persistence.mem.database":                       "Redis database number",
+	"alexandria.persistence.mem.sentinel.master_name":           "Redis Sentinel master name, Sentinel is used instead of host and port if set",
+	"alexandria.persistence.mem.sentinel.addresses":             "Redis Sentinel nodes (host:port)",
+	"alexandria.persistence.mem.sentinel.password":              "Redis Sentinel password",
+	"alexandria.persistence.mem.cluster.addresses":              "Redis Cluster seed nodes (host:port), Cluster is used instead of host and port if set",
+	"alexandria.persistence.mem.tls.enabled":                    "Enable TLS connections",
+	"alexandria.persistence.mem.tls.ca_file":                    "PEM-encoded certificate authorities file",
+	"alexandria.persistence.mem.tls.cert_file":                  "PEM-encoded client certificate file",
+	"alexandria.persistence.mem.tls.key_file":                   "PEM-encoded client key file",
+	"alexandria.persistence.mem.tls.insecure_skip_verify":       "Skip server certificate verification",
+	"alexandria.persistence.mem.connect.retries":                "Startup ping attempts after the first failure",
+	"alexandria.persistence.mem.connect.backoff":                "Initial backoff between startup pings, doubled on every attempt",
+	"alexandria.persistence.mem.connect.timeout":                "Dial timeout",
+	"alexandria.persistence.doc.collection":                     "Document collection (table) name",
+	"alexandria.persistence.doc.partition_key":                  "Document partition key",
+	"alexandria.persistence.doc.sort_key":                       "Document sort key",
+	"alexandria.persistence.doc.allow_scan":                     "Allow full collection scans",
+	"alexandria.persistence.doc.revision_field":                 "Document field holding the revision used by optimistic locking",
+	"alexandria.persistence.doc.dynamodb.region":                "DynamoDB region, taken from the AWS environment if empty",
+	"alexandria.persistence.doc.dynamodb.endpoint":              "DynamoDB endpoint, allows local stand-ins (e.g. http://localhost:8000 for DynamoDB Local)",
+	"alexandria.persistence.doc.dynamodb.provision":             "Create the table and its indexes if missing, meant for local development",
+	"alexandria.persistence.doc.dynamodb.billing_mode":          "Provisioned table billing mode (PAY_PER_REQUEST or PROVISIONED)",
+	"alexandria.persistence.doc.dynamodb.read_capacity":         "Provisioned table and indexes read capacity units",
+	"alexandria.persistence.doc.dynamodb.write_capacity":        "Provisioned table and indexes write capacity units",
+	"alexandria.persistence.doc.dynamodb.attribute_types":       "Key attribute types (S, N or B) by attribute name, keys are strings by default",
+	"alexandria.persistence.doc.dynamodb.indexes":               "Global secondary indexes provisioned along the table",
+	"alexandria.cloud.aws.cognito.pool":                         "AWS Cognito user pool ID",
+	"alexandria.cloud.aws.cognito.client":                       "AWS Cognito client ID",
+	"alexandria.security.auth.jwt.secret":                       "JWT signing secret, might reference a secret provider (secret://vault/auth#jwt_secret)",
+	"alexandria.features.store":                                 "Feature flag store (config, file or redis)",
+	"alexandria.features.file":                                  "JSON flags file used by the file store",
+	"alexandria.features.redis_key":                             "Redis hash used by the redis store",
+	"alexandria.features.flags":                                 "Flags used by the config store, reloaded at runtime",
+	"alexandria.secrets.file.dir":                               "Mounted secrets directory (e.g. Kubernetes secret volumes)",
+	"alexandria.secrets.vault.address":                          "Hashicorp Vault address",
+	"alexandria.secrets.vault.token":                            "Hashicorp Vault token, uses VAULT_TOKEN OS env variable if empty",
+	"alexandria.secrets.vault.mount":                            "Hashicorp Vault KV version 2 mount",
+	"alexandria.secrets.aws.region":                             "AWS Parameter Store and Secrets Manager region",
+	"alexandria.secrets.aws.endpoint":                           "AWS Parameter Store and Secrets Manager endpoint, allows local stand-ins",
+	"alexandria.secrets.kms.keeper":                             "gocloud.dev secrets keeper URL (e.g. awskms://alias/alexandria?region=us-east-1)",
+}
+
+// listItems Struct types of list keys, documented in generated schemas
+var listItems = map[string]interface{}{
+	"alexandria.eventbus.kafka.topics":            KafkaTopic{},
+	"alexandria.persistence.doc.dynamodb.indexes": DocstoreIndex{},
+	"alexandria.features.flags":                   FeatureFlag{},
+	"alexandria.service.resiliency.policies":      ResiliencyPolicy{},
+}
+
+// WriteSample writes a documented sample configuration file (YAML) holding every kernel and custom
+// section default value
+func (l *Loader) WriteSample(w io.Writer) error {
+	settings, docs := l.defaults()
+	b := new(strings.Builder)
+	writeSampleMap(b, settings, docs, "", 0)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSONSchema writes a JSON Schema (draft-07) describing every kernel and custom section key,
+// useful for editor validation of config files
+func (l *Loader) WriteJSONSchema(w io.Writer) error {
+	settings, docs := l.defaults()
+	schema := schemaObject(settings, docs, "")
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "Alexandria configuration"
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
+// defaults returns the nested default settings and key descriptions
+func (l *Loader) defaults() (map[string]interface{}, map[string]string) {
+	v := viper.New()
+	setKernelDefaults(v)
+	setSectionDefaults(v, l.sections)
+
+	docs := make(map[string]string, len(descriptions))
+	for k, d := range descriptions {
+		docs[k] = d
+	}
+	for _, s := range l.sections {
+		key := strings.ToLower(s.Key)
+		if s.Description != "" {
+			docs[key] = s.Description
+		}
+		for k, d := range s.Descriptions {
+			docs[key+"."+strings.ToLower(k)] = d
+		}
+	}
+
+	return v.AllSettings(), docs
+}
+
+func writeSampleMap(b *strings.Builder, settings map[string]interface{}, docs map[string]string, prefix string,
+	depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, k := range sortedKeys(settings) {
+		key := joinKey(prefix, k)
+		if doc, ok := docs[key]; ok {
+			fmt.Fprintf(b, "%s# %s\n", indent, doc)
+		}
+
+		switch value := settings[k].(type) {
+		case map[string]interface{}:
+			fmt.Fprintf(b, "%s%s:\n", indent, k)
+			writeSampleMap(b, value, docs, key, depth+1)
+		default:
+			items := reflect.ValueOf(value)
+			if value != nil && items.Kind() == reflect.Map && items.Len() == 0 {
+				fmt.Fprintf(b, "%s%s: {}\n", indent, k)
+				continue
+			}
+			if value != nil && items.Kind() == reflect.Slice {
+				if items.Len() == 0 {
+					fmt.Fprintf(b, "%s%s: []\n", indent, k)
+					continue
+				}
+				fmt.Fprintf(b, "%s%s:\n", indent, k)
+				for i := 0; i < items.Len(); i++ {
+					fmt.Fprintf(b, "%s  - %s\n", indent, sampleValue(items.Index(i).Interface()))
+				}
+				continue
+			}
+			fmt.Fprintf(b, "%s%s: %s\n", indent, k, sampleValue(value))
+		}
+	}
+}
+
+func sampleValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Duration:
+		return fmt.Sprintf("%q", v.String())
+	case string:
+		return fmt.Sprintf("%q", v)
+	case nil:
+		return `""`
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func schemaObject(settings map[string]interface{}, docs map[string]string, prefix string) map[string]interface{} {
+	properties := make(map[string]interface{}, len(settings))
+	for k, value := range settings {
+		key := joinKey(prefix, k)
+		var property map[string]interface{}
+		if m, ok := value.(map[string]interface{}); ok {
+			property = schemaObject(m, docs, key)
+		} else {
+			property = schemaValue(key, value)
+		}
+		if doc, ok := docs[key]; ok {
+			property["description"] = doc
+		}
+		properties[k] = property
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+func schemaValue(key string, value interface{}) map[string]interface{} {
+	if d, ok := value.(time.Duration); ok {
+		return map[string]interface{}{
+			"type":    "string",
+			"pattern": `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`,
+			"default": d.String(),
+		}
+	}
+
+	property := map[string]interface{}{
+		"type": schemaType(reflect.TypeOf(value)),
+	}
+	if value == nil {
+		return property
+	}
+
+	if property["type"] == "array" {
+		if item, ok := listItems[key]; ok {
+			property["items"] = schemaStruct(reflect.TypeOf(item))
+		} else {
+			property["items"] = map[string]interface{}{"type": schemaType(reflect.TypeOf(value).Elem())}
+		}
+	}
+	property["default"] = value
+
+	return property
+}
+
+// schemaStruct returns the schema of a struct decoded using mapstructure tags
+func schemaStruct(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("mapstructure")
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		if f.Type == reflect.TypeOf(time.Duration(0)) {
+			properties[name] = map[string]interface{}{"type": "string"}
+			continue
+		}
+		property := map[string]interface{}{"type": schemaType(f.Type)}
+		if f.Type.Kind() == reflect.Slice {
+			property["items"] = map[string]interface{}{"type": schemaType(f.Type.Elem())}
+		}
+		properties[name] = property
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+func schemaType(t reflect.Type) string {
+	if t == nil {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Ptr:
+		return schemaType(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "string"
+	}
+}
+
+func sortedKeys(settings map[string]interface{}) []string {
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
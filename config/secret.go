@@ -0,0 +1,140 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// SecretScheme Prefix used by configuration values referencing a secret,
+// e.g. secret://vault/db#password
+const SecretScheme = "secret://"
+
+// Secrets Secret providers settings
+type Secrets struct {
+	FileDir      string
+	VaultAddress string
+	VaultToken   string
+	VaultMount   string
+	AWSRegion    string
+	AWSEndpoint  string
+	KMSKeeper    string
+}
+
+func setSecretsDefaults(v *viper.Viper) {
+	v.SetDefault("alexandria.secrets.file.dir", "/etc/alexandria/secrets")
+	v.SetDefault("alexandria.secrets.vault.address", "")
+	v.SetDefault("alexandria.secrets.vault.token", "")
+	v.SetDefault("alexandria.secrets.vault.mount", "secret")
+	v.SetDefault("alexandria.secrets.aws.region", "")
+	v.SetDefault("alexandria.secrets.aws.endpoint", "")
+	v.SetDefault("alexandria.secrets.kms.keeper", "")
+}
+
+func newSecretsConfig(v *viper.Viper) Secrets {
+	return Secrets{
+		FileDir:      v.GetString("alexandria.secrets.file.dir"),
+		VaultAddress: v.GetString("alexandria.secrets.vault.address"),
+		VaultToken:   v.GetString("alexandria.secrets.vault.token"),
+		VaultMount:   v.GetString("alexandria.secrets.vault.mount"),
+		AWSRegion:    v.GetString("alexandria.secrets.aws.region"),
+		AWSEndpoint:  v.GetString("alexandria.secrets.aws.endpoint"),
+		KMSKeeper:    v.GetString("alexandria.secrets.kms.keeper"),
+	}
+}
+
+// WithSecretProvider registers a secret provider under the given name into the Loader, replaces the
+// default provider if the name is already taken (vault, ssm, secretsmanager, kms or file)
+func WithSecretProvider(name string, provider SecretProvider) LoaderOption {
+	return func(l *Loader) {
+		if l.secretProviders == nil {
+			l.secretProviders = make(map[string]SecretProvider)
+		}
+		l.secretProviders[name] = provider
+	}
+}
+
+// ParseSecretURI returns the provider, path and key from a secret reference
+// (secret://provider/path#key)
+func ParseSecretURI(uri string) (provider, path, key string, err error) {
+	if !strings.HasPrefix(uri, SecretScheme) {
+		return "", "", "", fmt.Errorf("secret reference %s must start with %s", uri, SecretScheme)
+	}
+
+	ref := strings.TrimPrefix(uri, SecretScheme)
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		ref, key = ref[:i], ref[i+1:]
+	}
+
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("secret reference %s must follow %sprovider/path#key", uri, SecretScheme)
+	}
+
+	return parts[0], parts[1], key, nil
+}
+
+// resolveSecrets replaces every configuration value referencing a secret with the secret's value,
+// registered providers are preferred over default providers
+func resolveSecrets(ctx context.Context, v *viper.Viper, cfg Secrets, registered map[string]SecretProvider) error {
+	providers := make(map[string]SecretProvider)
+	for _, k := range v.AllKeys() {
+		value, ok := v.Get(k).(string)
+		if !ok || !strings.HasPrefix(value, SecretScheme) {
+			continue
+		}
+
+		name, path, key, err := ParseSecretURI(value)
+		if err != nil {
+			return fmt.Errorf("config %s: %w", k, err)
+		}
+
+		provider, ok := providers[name]
+		if !ok {
+			provider, err = getSecretProvider(ctx, name, cfg, registered)
+			if err != nil {
+				return fmt.Errorf("config %s: %w", k, err)
+			}
+			providers[name] = provider
+		}
+
+		secret, err := provider.GetSecret(ctx, path, key)
+		if err != nil {
+			return fmt.Errorf("config %s: %w", k, err)
+		}
+		v.Set(k, secret)
+	}
+
+	return nil
+}
+
+// getSecretProvider returns a registered secret provider or a default provider built from configuration
+func getSecretProvider(ctx context.Context, name string, cfg Secrets,
+	registered map[string]SecretProvider) (SecretProvider, error) {
+	if provider, ok := registered[name]; ok {
+		return provider, nil
+	}
+
+	switch name {
+	case "file":
+		return NewFileSecretProvider(cfg.FileDir), nil
+	case "vault":
+		if cfg.VaultAddress == "" {
+			return nil, fmt.Errorf("secret provider vault requires alexandria.secrets.vault.address")
+		}
+		return NewVaultSecretProvider(cfg.VaultAddress, cfg.VaultToken, cfg.VaultMount), nil
+	case "ssm":
+		return NewParameterStoreSecretProvider(cfg.AWSRegion, cfg.AWSEndpoint)
+	case "secretsmanager":
+		return NewSecretsManagerSecretProvider(cfg.AWSRegion, cfg.AWSEndpoint)
+	case "kms":
+		if cfg.KMSKeeper == "" {
+			return nil, fmt.Errorf("secret provider kms requires alexandria.secrets.kms.keeper")
+		}
+		return NewKeeperSecretProvider(ctx, cfg.KMSKeeper)
+	default:
+		return nil, fmt.Errorf("secret provider %s is not registered", name)
+	}
+}
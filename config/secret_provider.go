@@ -0,0 +1,276 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	gcsecrets "gocloud.dev/secrets"
+	_ "gocloud.dev/secrets/awskms"
+	_ "gocloud.dev/secrets/localsecrets"
+)
+
+// SecretProvider Fetches secrets from an external secret store
+type SecretProvider interface {
+	// GetSecret returns the secret stored at the given path, key selects a single field from
+	// structured (JSON) secrets
+	GetSecret(ctx context.Context, path, key string) (string, error)
+}
+
+// FileSecretProvider Reads secrets from mounted files (e.g. Kubernetes secret volumes),
+// secret://file/db#password reads <dir>/db/password
+type FileSecretProvider struct {
+	Dir string
+}
+
+// NewFileSecretProvider returns a mounted-file secret provider
+func NewFileSecretProvider(dir string) *FileSecretProvider {
+	return &FileSecretProvider{Dir: dir}
+}
+
+func (p *FileSecretProvider) GetSecret(_ context.Context, path, key string) (string, error) {
+	file, err := p.secretFile(filepath.FromSlash(path))
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+
+	// Kubernetes mounts every secret key as a file
+	if info.IsDir() {
+		keyFile, err := p.secretFile(filepath.FromSlash(path), key)
+		if err != nil {
+			return "", err
+		}
+		value, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(value), "\r\n"), nil
+	}
+
+	value, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return selectSecretKey(strings.TrimRight(string(value), "\r\n"), key)
+}
+
+// secretFile joins the given path elements to the secrets directory, returns an error if the resulting
+// path is not under the directory (e.g. secret://file/../../etc/shadow)
+func (p *FileSecretProvider) secretFile(elem ...string) (string, error) {
+	dir := filepath.Clean(p.Dir)
+	file := filepath.Join(append([]string{dir}, elem...)...)
+	rel, err := filepath.Rel(dir, file)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("secret path %s is not under %s", filepath.Join(elem...), p.Dir)
+	}
+
+	return file, nil
+}
+
+// VaultSecretProvider Reads secrets from Hashicorp Vault's KV version 2 secret engine,
+// secret://vault/db#password reads the password field from <mount>/data/db
+type VaultSecretProvider struct {
+	Address string
+	Token   string
+	Mount   string
+	Client  *http.Client
+}
+
+// NewVaultSecretProvider returns a Hashicorp Vault KV secret provider, uses VAULT_TOKEN
+// OS env variable if token is empty
+func NewVaultSecretProvider(address, token, mount string) *VaultSecretProvider {
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if mount == "" {
+		mount = "secret"
+	}
+
+	return &VaultSecretProvider{
+		Address: strings.TrimSuffix(address, "/"),
+		Token:   token,
+		Mount:   strings.Trim(mount, "/"),
+		Client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (p *VaultSecretProvider) GetSecret(ctx context.Context, path, key string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/%s/data/%s", p.Address, p.Mount,
+		strings.Trim(path, "/")), nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("X-Vault-Token", p.Token)
+
+	res, err := p.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault responded with status %d for secret %s", res.StatusCode, path)
+	}
+
+	secret := struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}{}
+	if err = json.NewDecoder(res.Body).Decode(&secret); err != nil {
+		return "", err
+	}
+
+	return selectSecretField(secret.Data.Data, path, key)
+}
+
+// ParameterStoreSecretProvider Reads secrets from AWS Systems Manager Parameter Store, SecureString
+// parameters are decrypted, secret://ssm/prod/db/password reads /prod/db/password
+type ParameterStoreSecretProvider struct {
+	client *ssm.SSM
+}
+
+// NewParameterStoreSecretProvider returns an AWS Parameter Store secret provider, endpoint
+// is optional and allows local stand-ins
+func NewParameterStoreSecretProvider(region, endpoint string) (*ParameterStoreSecretProvider, error) {
+	sess, err := newAWSSession(region, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ParameterStoreSecretProvider{client: ssm.New(sess)}, nil
+}
+
+func (p *ParameterStoreSecretProvider) GetSecret(ctx context.Context, path, key string) (string, error) {
+	out, err := p.client.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           awssdk.String("/" + strings.TrimPrefix(path, "/")),
+		WithDecryption: awssdk.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return selectSecretKey(awssdk.StringValue(out.Parameter.Value), key)
+}
+
+// SecretsManagerSecretProvider Reads secrets from AWS Secrets Manager,
+// secret://secretsmanager/prod/db#password reads the password field from prod/db
+type SecretsManagerSecretProvider struct {
+	client *secretsmanager.SecretsManager
+}
+
+// NewSecretsManagerSecretProvider returns an AWS Secrets Manager secret provider, endpoint
+// is optional and allows local stand-ins
+func NewSecretsManagerSecretProvider(region, endpoint string) (*SecretsManagerSecretProvider, error) {
+	sess, err := newAWSSession(region, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretsManagerSecretProvider{client: secretsmanager.New(sess)}, nil
+}
+
+func (p *SecretsManagerSecretProvider) GetSecret(ctx context.Context, path, key string) (string, error) {
+	out, err := p.client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: awssdk.String(path),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return selectSecretKey(awssdk.StringValue(out.SecretString), key)
+}
+
+// KeeperSecretProvider Decrypts base64-encoded ciphertexts using a gocloud.dev secrets keeper
+// (AWS KMS or local keys), secret://kms/<base64 ciphertext>
+type KeeperSecretProvider struct {
+	Keeper *gcsecrets.Keeper
+}
+
+// NewKeeperSecretProvider returns a secret provider using the keeper from the given URL
+// (e.g. awskms://alias/alexandria?region=us-east-1, base64key://<key>)
+func NewKeeperSecretProvider(ctx context.Context, keeperURL string) (*KeeperSecretProvider, error) {
+	keeper, err := gcsecrets.OpenKeeper(ctx, keeperURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeeperSecretProvider{Keeper: keeper}, nil
+}
+
+func (p *KeeperSecretProvider) GetSecret(ctx context.Context, path, key string) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(path)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := p.Keeper.Decrypt(ctx, ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	return selectSecretKey(string(plaintext), key)
+}
+
+func newAWSSession(region, endpoint string) (*session.Session, error) {
+	cfg := awssdk.NewConfig()
+	if region != "" {
+		cfg = cfg.WithRegion(region)
+	}
+	if endpoint != "" {
+		cfg = cfg.WithEndpoint(endpoint)
+	}
+
+	return session.NewSession(cfg)
+}
+
+// selectSecretKey returns the given key from a JSON-encoded secret, returns the whole secret if
+// key is empty
+func selectSecretKey(secret, key string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, cannot select key %s", key)
+	}
+
+	return selectSecretField(fields, "", key)
+}
+
+func selectSecretField(fields map[string]interface{}, path, key string) (string, error) {
+	if key == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("secret %s has %d fields, a key is required", path, len(fields))
+		}
+		for _, v := range fields {
+			return fmt.Sprint(v), nil
+		}
+	}
+
+	v, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", path, key)
+	}
+
+	return fmt.Sprint(v), nil
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gocloud.dev/secrets/localsecrets"
+)
+
+func TestParseSecretURI(t *testing.T) {
+	provider, path, key, err := ParseSecretURI("secret://vault/prod/db#password")
+	assert.Nil(t, err)
+	assert.Equal(t, "vault", provider)
+	assert.Equal(t, "prod/db", path)
+	assert.Equal(t, "password", key)
+
+	_, path, key, err = ParseSecretURI("secret://ssm/prod/db/password")
+	assert.Nil(t, err)
+	assert.Equal(t, "prod/db/password", path)
+	assert.Empty(t, key)
+
+	_, _, _, err = ParseSecretURI("vault/prod/db#password")
+	assert.NotNil(t, err)
+	_, _, _, err = ParseSecretURI("secret://vault")
+	assert.NotNil(t, err)
+}
+
+func TestResolveSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// Kubernetes secret volume and JSON secret file
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "db"), 0700))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "db", "password"), []byte("postgres\n"), 0600))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "redis.json"), []byte(`{"password":"redis"}`), 0600))
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" || r.URL.Path != "/v1/secret/data/auth" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data": map[string]string{"jwt_secret": "vault_secret"},
+			},
+		})
+	}))
+	defer vault.Close()
+
+	ctx := context.Background()
+	key, err := localsecrets.NewRandomKey()
+	assert.Nil(t, err)
+	keeper := localsecrets.NewKeeper(key)
+	ciphertext, err := keeper.Encrypt(ctx, []byte("kms_secret"))
+	assert.Nil(t, err)
+	l := NewLoader(WithSecretProvider("kms", &KeeperSecretProvider{Keeper: keeper}))
+
+	v := viper.New()
+	v.Set("alexandria.persistence.dbms.password", "secret://file/db#password")
+	v.Set("alexandria.persistence.mem.password", "secret://file/redis.json#password")
+	v.Set("alexandria.security.auth.jwt.secret", "secret://vault/auth#jwt_secret")
+	v.Set("alexandria.cloud.aws.secret", "secret://kms/"+base64.StdEncoding.EncodeToString(ciphertext))
+	v.Set("alexandria.info.service", "example-service")
+
+	err = resolveSecrets(ctx, v, Secrets{
+		FileDir:      dir,
+		VaultAddress: vault.URL,
+		VaultToken:   "root",
+		VaultMount:   "secret",
+	}, l.secretProviders)
+	assert.Nil(t, err)
+	assert.Equal(t, "postgres", v.GetString("alexandria.persistence.dbms.password"))
+	assert.Equal(t, "redis", v.GetString("alexandria.persistence.mem.password"))
+	assert.Equal(t, "vault_secret", v.GetString("alexandria.security.auth.jwt.secret"))
+	assert.Equal(t, "kms_secret", v.GetString("alexandria.cloud.aws.secret"))
+	assert.Equal(t, "example-service", v.GetString("alexandria.info.service"))
+
+	v.Set("alexandria.security.auth.jwt.secret", "secret://unknown/auth#jwt_secret")
+	assert.NotNil(t, resolveSecrets(ctx, v, Secrets{}, nil))
+
+	// File references must not escape the secrets directory
+	outside, err := ioutil.TempDir("", "outside")
+	assert.Nil(t, err)
+	defer os.RemoveAll(outside)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(outside, "shadow"), []byte("root"), 0600))
+	provider := NewFileSecretProvider(dir)
+	rel, err := filepath.Rel(dir, outside)
+	assert.Nil(t, err)
+	for _, ref := range [][2]string{{filepath.ToSlash(rel) + "/shadow", ""}, {"db", "../../" + filepath.Base(outside) +
+		"/shadow"}, {"..", ""}, {"db/../..", ""}} {
+		_, err = provider.GetSecret(ctx, ref[0], ref[1])
+		assert.NotNil(t, err, ref)
+	}
+	value, err := provider.GetSecret(ctx, "db/../db", "password")
+	assert.Nil(t, err)
+	assert.Equal(t, "postgres", value)
+}
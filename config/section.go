@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// Section Service-specific configuration section mapped into Kernel.Sections
+type Section struct {
+	// Key Section's root key, e.g. search maps search.index.shards
+	Key string
+	// Description Documents the section in generated samples and schemas
+	Description string
+	// Defaults Default values keyed by path relative to Key (e.g. index.shards)
+	Defaults map[string]interface{}
+	// Descriptions Documents keys relative to Key in generated samples and schemas
+	Descriptions map[string]string
+	// New returns a pointer to the section's struct, decoded using mapstructure tags. Values
+	// implementing Validate() error are validated along with the Kernel
+	New func() interface{}
+}
+
+// WithSections registers custom configuration sections into the Kernel
+func WithSections(sections ...Section) LoaderOption {
+	return func(l *Loader) {
+		l.sections = append(l.sections, sections...)
+	}
+}
+
+func setSectionDefaults(v *viper.Viper, sections []Section) {
+	for _, s := range sections {
+		for k, value := range s.Defaults {
+			v.SetDefault(s.Key+"."+k, value)
+		}
+	}
+}
+
+// newSections decodes every custom section, OS env overrides are included
+func newSections(v *viper.Viper, sections []Section) (map[string]interface{}, error) {
+	mapped := make(map[string]interface{}, len(sections))
+	if len(sections) == 0 {
+		return mapped, nil
+	}
+
+	settings := v.AllSettings()
+	for _, s := range sections {
+		target := s.New()
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				mapstructure.StringToTimeDurationHookFunc(),
+				mapstructure.StringToSliceHookFunc(","),
+			),
+			WeaklyTypedInput: true,
+			Result:           target,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = decoder.Decode(lookupSetting(settings, s.Key)); err != nil {
+			return nil, fmt.Errorf("config section %s: %w", s.Key, err)
+		}
+		mapped[s.Key] = target
+	}
+
+	return mapped, nil
+}
+
+// lookupSetting returns the nested setting stored at the given dotted key
+func lookupSetting(settings map[string]interface{}, key string) interface{} {
+	var value interface{} = settings
+	for _, part := range strings.Split(strings.ToLower(key), ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[part]
+	}
+
+	return value
+}
@@ -2,22 +2,23 @@ package config
 
 import "github.com/spf13/viper"
 
-type tracing struct {
+// Tracing Distributed tracing settings
+type Tracing struct {
 	ZipkinHost     string
 	ZipkinEndpoint string
 	ZipkinBridge   bool
 }
 
-func init() {
-	viper.SetDefault("alexandria.tracing.zipkin.host", "http://localhost:9411/api/v2/spans")
-	viper.SetDefault("alexandria.tracing.zipkin.endpoint", "0.0.0.0:8080")
-	viper.SetDefault("alexandria.tracing.zipkin.bridge", true)
+func setTracingDefaults(v *viper.Viper) {
+	v.SetDefault("alexandria.tracing.zipkin.host", "http://localhost:9411/api/v2/spans")
+	v.SetDefault("alexandria.tracing.zipkin.endpoint", "0.0.0.0:8080")
+	v.SetDefault("alexandria.tracing.zipkin.bridge", true)
 }
 
-func newTracingConfig() tracing {
-	return tracing{
-		ZipkinHost:     viper.GetString("alexandria.tracing.zipkin.host"),
-		ZipkinEndpoint: viper.GetString("alexandria.tracing.zipkin.endpoint"),
-		ZipkinBridge:   viper.GetBool("alexandria.tracing.zipkin.bridge"),
+func newTracingConfig(v *viper.Viper) Tracing {
+	return Tracing{
+		ZipkinHost:     v.GetString("alexandria.tracing.zipkin.host"),
+		ZipkinEndpoint: v.GetString("alexandria.tracing.zipkin.endpoint"),
+		ZipkinBridge:   v.GetBool("alexandria.tracing.zipkin.bridge"),
 	}
 }
@@ -2,28 +2,29 @@ package config
 
 import "github.com/spf13/viper"
 
-type transport struct {
+// Transport HTTP and gRPC transport settings
+type Transport struct {
 	HTTPHost string
 	HTTPPort int
 	RPCHost  string
 	RPCPort  int
 }
 
-func init() {
+func setTransportDefaults(v *viper.Viper) {
 	// HTTP
-	viper.SetDefault("alexandria.service.transport.http.host", "0.0.0.0")
-	viper.SetDefault("alexandria.service.transport.http.port", 8080)
+	v.SetDefault("alexandria.service.transport.http.host", "0.0.0.0")
+	v.SetDefault("alexandria.service.transport.http.port", 8080)
 
 	// RPC
-	viper.SetDefault("alexandria.service.transport.rpc.host", "0.0.0.0")
-	viper.SetDefault("alexandria.service.transport.rpc.port", 31337)
+	v.SetDefault("alexandria.service.transport.rpc.host", "0.0.0.0")
+	v.SetDefault("alexandria.service.transport.rpc.port", 31337)
 }
 
-func newTransportConfig() transport {
-	return transport{
-		HTTPHost: viper.GetString("alexandria.service.transport.http.host"),
-		HTTPPort: viper.GetInt("alexandria.service.transport.http.port"),
-		RPCHost:  viper.GetString("alexandria.service.transport.rpc.host"),
-		RPCPort:  viper.GetInt("alexandria.service.transport.rpc.port"),
+func newTransportConfig(v *viper.Viper) Transport {
+	return Transport{
+		HTTPHost: v.GetString("alexandria.service.transport.http.host"),
+		HTTPPort: v.GetInt("alexandria.service.transport.http.port"),
+		RPCHost:  v.GetString("alexandria.service.transport.rpc.host"),
+		RPCPort:  v.GetInt("alexandria.service.transport.rpc.port"),
 	}
 }
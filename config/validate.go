@@ -0,0 +1,418 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/alexandria-oss/core/exception"
+)
+
+const (
+	// ProfileDevelopment Local development profile
+	ProfileDevelopment = "dev"
+	// ProfileStaging Pre-production profile
+	ProfileStaging = "staging"
+	// ProfileProduction Production profile, example values are rejected
+	ProfileProduction = "prod"
+)
+
+// ErrExampleValue A configuration value was left at its example default
+var ErrExampleValue = errors.New("config value is left at its example default")
+
+// ValidationError Every problem found while validating a Kernel configuration
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return fmt.Sprintf("invalid configuration (%d errors): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Is reports whether any validation error matches target
+func (e *ValidationError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate verifies the kernel configuration, returns a *ValidationError listing every problem
+func (k *Kernel) Validate() error {
+	v := new(ValidationError)
+	add := func(err error) {
+		if err != nil {
+			v.Errors = append(v.Errors, err)
+		}
+	}
+
+	switch k.Profile {
+	case ProfileDevelopment, ProfileStaging, ProfileProduction:
+	default:
+		add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+			"alexandria.info.profile", strings.Join([]string{ProfileDevelopment, ProfileStaging, ProfileProduction}, ", "))))
+	}
+	if k.Service == "" {
+		add(requiredField("alexandria.info.service"))
+	}
+
+	// Transport
+	add(validatePort("alexandria.service.transport.http.port", k.Transport.HTTPPort))
+	add(validatePort("alexandria.service.transport.rpc.port", k.Transport.RPCPort))
+
+	// Resiliency
+	for _, err := range k.Resiliency.decodeErrors {
+		add(err)
+	}
+	if k.Resiliency.RateLimit <= 0 {
+		add(invalidRange("alexandria.service.resiliency.rate_limit", "0", "+Inf"))
+	}
+	if k.Resiliency.RateBurst <= 0 {
+		add(invalidRange("alexandria.service.resiliency.rate_burst", "1", "+Inf"))
+	}
+	switch k.Resiliency.Limiter.Store {
+	case "memory", "redis":
+	default:
+		add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+			"alexandria.service.resiliency.limiter.store", "memory or redis")))
+	}
+	switch k.Resiliency.Limiter.Algorithm {
+	case "token_bucket":
+	case "sliding_window":
+		if k.Resiliency.Limiter.Window <= 0 {
+			add(invalidRange("alexandria.service.resiliency.limiter.window", "0", "+Inf"))
+		}
+	default:
+		add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+			"alexandria.service.resiliency.limiter.algorithm", "token_bucket or sliding_window")))
+	}
+	for i, proxy := range k.Resiliency.Limiter.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+				fmt.Sprintf("alexandria.service.resiliency.limiter.trusted_proxies[%d]", i), "CIDR or IP")))
+		}
+	}
+	validatePolicy := func(key string, p ResiliencyPolicy) {
+		if p.Timeout < 0 {
+			add(invalidRange(key+".timeout", "0", "+Inf"))
+		}
+		if p.Retries < 0 {
+			add(invalidRange(key+".retries", "0", "+Inf"))
+		}
+		if p.Retries > 0 && p.RetryBackoff <= 0 {
+			add(invalidRange(key+".retry_backoff", "0", "+Inf"))
+		}
+		if p.RetryMaxBackoff < p.RetryBackoff {
+			add(invalidRange(key+".retry_max_backoff", key+".retry_backoff", "+Inf"))
+		}
+		if p.MaxConcurrent < 0 {
+			add(invalidRange(key+".max_concurrent", "0", "+Inf"))
+		}
+		if p.BreakerTimeout <= 0 {
+			add(invalidRange(key+".breaker_timeout", "0", "+Inf"))
+		}
+	}
+	validatePolicy("alexandria.service.resiliency.policy", k.Resiliency.Policy)
+	for i, p := range k.Resiliency.Policies {
+		key := fmt.Sprintf("alexandria.service.resiliency.policies[%d]", i)
+		if p.Service == "" {
+			add(requiredField(key + ".service"))
+		}
+		validatePolicy(key, p)
+	}
+
+	// Tracing and logging
+	add(validateURL("alexandria.tracing.zipkin.host", k.Tracing.ZipkinHost))
+	add(validateAddress("alexandria.tracing.zipkin.endpoint", k.Tracing.ZipkinEndpoint))
+	switch k.Logging.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+			"alexandria.log.level", "debug, info, warn or error")))
+	}
+
+	// Event bus
+	for _, err := range k.EventBus.decodeErrors {
+		add(err)
+	}
+	if len(k.EventBus.KafkaBrokers) == 0 {
+		add(requiredField("alexandria.eventbus.kafka.brokers"))
+	}
+	for i, broker := range k.EventBus.KafkaBrokers {
+		add(validateAddress(fmt.Sprintf("alexandria.eventbus.kafka.brokers[%d]", i), broker))
+	}
+	if _, err := sarama.ParseKafkaVersion(k.EventBus.KafkaVersion); err != nil {
+		add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+			"alexandria.eventbus.kafka.version", "a Kafka version (e.g. 2.4.0)")))
+	}
+	if k.EventBus.KafkaSASL.Enabled {
+		switch k.EventBus.KafkaSASL.Mechanism {
+		case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+		default:
+			add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+				"alexandria.eventbus.kafka.sasl.mechanism", "PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512")))
+		}
+		if k.EventBus.KafkaSASL.User == "" {
+			add(requiredField("alexandria.eventbus.kafka.sasl.user"))
+		}
+	}
+	if (k.EventBus.KafkaTLS.CertFile == "") != (k.EventBus.KafkaTLS.KeyFile == "") {
+		add(requiredField("alexandria.eventbus.kafka.tls.cert_file and alexandria.eventbus.kafka.tls.key_file"))
+	}
+	switch k.EventBus.KafkaConsumer.InitialOffset {
+	case "newest", "oldest":
+	default:
+		add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+			"alexandria.eventbus.kafka.consumer.initial_offset", "newest or oldest")))
+	}
+	for i, topic := range k.EventBus.KafkaTopics {
+		if topic.Name == "" {
+			add(requiredField(fmt.Sprintf("alexandria.eventbus.kafka.topics[%d].name", i)))
+		}
+		if topic.Partitions < 0 {
+			add(invalidRange(fmt.Sprintf("alexandria.eventbus.kafka.topics[%d].partitions", i), "0", "2147483648"))
+		}
+		if topic.ReplicationFactor < 0 {
+			add(invalidRange(fmt.Sprintf("alexandria.eventbus.kafka.topics[%d].replication_factor", i), "0", "32768"))
+		}
+	}
+
+	// Persistence
+	if k.DBMS.Driver == "" {
+		add(requiredField("alexandria.persistence.dbms.driver"))
+	}
+	add(validateURL("alexandria.persistence.dbms.url", k.DBMS.URL))
+	if k.DBMS.URL == "" {
+		add(validatePort("alexandria.persistence.dbms.port", k.DBMS.Port))
+		if k.DBMS.Host == "" {
+			add(requiredField("alexandria.persistence.dbms.host"))
+		}
+	}
+	if k.DBMS.Pool.MaxOpenConns < 0 {
+		add(invalidRange("alexandria.persistence.dbms.pool.max_open_conns", "0", "+Inf"))
+	}
+	if k.DBMS.Pool.MaxIdleConns < 0 {
+		add(invalidRange("alexandria.persistence.dbms.pool.max_idle_conns", "0", "+Inf"))
+	}
+	switch k.DBMS.TLS.Mode {
+	case "", "disable", "require", "verify-ca", "verify-full":
+	default:
+		add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+			"alexandria.persistence.dbms.tls.mode", "disable, require, verify-ca or verify-full")))
+	}
+	if k.DBMS.Connect.Retries < 0 {
+		add(invalidRange("alexandria.persistence.dbms.connect.retries", "0", "+Inf"))
+	}
+	if k.DBMS.Migrations.Dir != "" && k.DBMS.Migrations.Table == "" {
+		add(requiredField("alexandria.persistence.dbms.migrations.table"))
+	}
+	switch {
+	case k.InMemory.Sentinel.MasterName != "" && len(k.InMemory.Cluster.Addresses) > 0:
+		add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+			"alexandria.persistence.mem.sentinel.master_name", "empty if cluster addresses are set")))
+	case k.InMemory.Sentinel.MasterName != "":
+		if len(k.InMemory.Sentinel.Addresses) == 0 {
+			add(requiredField("alexandria.persistence.mem.sentinel.addresses"))
+		}
+		for i, address := range k.InMemory.Sentinel.Addresses {
+			add(validateAddress(fmt.Sprintf("alexandria.persistence.mem.sentinel.addresses[%d]", i), address))
+		}
+	case len(k.InMemory.Cluster.Addresses) > 0:
+		for i, address := range k.InMemory.Cluster.Addresses {
+			add(validateAddress(fmt.Sprintf("alexandria.persistence.mem.cluster.addresses[%d]", i), address))
+		}
+	default:
+		add(validatePort("alexandria.persistence.mem.port", k.InMemory.Port))
+	}
+	if k.InMemory.TLS.CertFile != "" && k.InMemory.TLS.KeyFile == "" {
+		add(requiredField("alexandria.persistence.mem.tls.key_file"))
+	}
+	if k.InMemory.Connect.Retries < 0 {
+		add(invalidRange("alexandria.persistence.mem.connect.retries", "0", "+Inf"))
+	}
+	if k.InMemory.Database != "" {
+		if _, err := strconv.Atoi(k.InMemory.Database); err != nil {
+			add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+				"alexandria.persistence.mem.database", "a database number")))
+		}
+	}
+	for _, err := range k.Docstore.DynamoDB.decodeErrors {
+		add(err)
+	}
+	add(validateURL("alexandria.persistence.doc.dynamodb.endpoint", k.Docstore.DynamoDB.Endpoint))
+	if k.Docstore.DynamoDB.Provision && k.Docstore.PartitionKey == "" {
+		add(requiredField("alexandria.persistence.doc.partition_key"))
+	}
+	switch k.Docstore.DynamoDB.BillingMode {
+	case "PAY_PER_REQUEST":
+	case "PROVISIONED":
+		if k.Docstore.DynamoDB.ReadCapacity <= 0 {
+			add(invalidRange("alexandria.persistence.doc.dynamodb.read_capacity", "1", "+Inf"))
+		}
+		if k.Docstore.DynamoDB.WriteCapacity <= 0 {
+			add(invalidRange("alexandria.persistence.doc.dynamodb.write_capacity", "1", "+Inf"))
+		}
+	default:
+		add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+			"alexandria.persistence.doc.dynamodb.billing_mode", "PAY_PER_REQUEST or PROVISIONED")))
+	}
+	for attr, kind := range k.Docstore.DynamoDB.AttributeTypes {
+		switch kind {
+		case "S", "N", "B":
+		default:
+			add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+				"alexandria.persistence.doc.dynamodb.attribute_types."+attr, "S, N or B")))
+		}
+	}
+	for i, index := range k.Docstore.DynamoDB.Indexes {
+		if index.Name == "" {
+			add(requiredField(fmt.Sprintf("alexandria.persistence.doc.dynamodb.indexes[%d].name", i)))
+		}
+		if index.PartitionKey == "" {
+			add(requiredField(fmt.Sprintf("alexandria.persistence.doc.dynamodb.indexes[%d].partition_key", i)))
+		}
+	}
+
+	// Feature flags
+	switch k.Features.Store {
+	case "config", "file", "redis":
+	default:
+		add(exception.NewErrorDescription(exception.InvalidFieldFormat, fmt.Sprintf(exception.InvalidFieldFormatString,
+			"alexandria.features.store", "config, file or redis")))
+	}
+	for _, err := range k.Features.decodeErrors {
+		add(err)
+	}
+	for i, flag := range k.Features.Flags {
+		if flag.Name == "" {
+			add(requiredField(fmt.Sprintf("alexandria.features.flags[%d].name", i)))
+		}
+		if flag.Percentage != nil && (*flag.Percentage < 0 || *flag.Percentage > 100) {
+			add(invalidRange(fmt.Sprintf("alexandria.features.flags[%d].percentage", i), "0", "101"))
+		}
+	}
+
+	// Secrets
+	add(validateURL("alexandria.secrets.vault.address", k.Secrets.VaultAddress))
+
+	if k.Profile == ProfileProduction {
+		// Example defaults must be replaced in production
+		examples := []struct {
+			key, value string
+			examples   []string
+		}{
+			{"alexandria.security.auth.jwt.secret", k.Auth.JWTSecret, []string{"example_secret", "example_secret_key"}},
+			{"alexandria.persistence.dbms.password", k.DBMS.Password, []string{"root"}},
+			{"alexandria.cloud.aws.cognito.pool", k.AWS.CognitoPoolID, []string{"example_pool_id"}},
+			{"alexandria.cloud.aws.cognito.client", k.AWS.CognitoClientID, []string{"example_client_id"}},
+		}
+		for _, e := range examples {
+			for _, example := range e.examples {
+				if e.value == example {
+					add(exception.NewErrorDescription(ErrExampleValue, e.key))
+				}
+			}
+		}
+		if k.Auth.JWTSecret == "" {
+			add(requiredField("alexandria.security.auth.jwt.secret"))
+		}
+		if u, err := url.Parse(k.DBMS.URL); err == nil && u.User != nil {
+			if password, ok := u.User.Password(); ok && password == "root" {
+				add(exception.NewErrorDescription(ErrExampleValue, "alexandria.persistence.dbms.url"))
+			}
+		}
+	}
+
+	// Custom sections
+	keys := make([]string, 0, len(k.Sections))
+	for key := range k.Sections {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		section, ok := k.Sections[key].(interface{ Validate() error })
+		if !ok {
+			continue
+		}
+		err := section.Validate()
+		if verr, ok := err.(*ValidationError); ok {
+			v.Errors = append(v.Errors, verr.Errors...)
+			continue
+		}
+		add(err)
+	}
+
+	if len(v.Errors) > 0 {
+		return v
+	}
+
+	return nil
+}
+
+func requiredField(key string) error {
+	return exception.NewErrorDescription(exception.RequiredField, fmt.Sprintf(exception.RequiredFieldString, key))
+}
+
+// decodeError reports a value which could not be decoded into its section
+func decodeError(key string, err error) error {
+	return exception.NewErrorDescription(exception.InvalidFieldFormat,
+		fmt.Sprintf("config key %s could not be decoded, %s", key, strings.ReplaceAll(err.Error(), "\n", " ")))
+}
+
+func invalidRange(key, min, max string) error {
+	return exception.NewErrorDescription(exception.InvalidFieldRange,
+		fmt.Sprintf(exception.InvalidFieldRangeString, key, min, max))
+}
+
+func validatePort(key string, port int) error {
+	if port <= 0 || port > 65535 {
+		return invalidRange(key, "1", "65536")
+	}
+
+	return nil
+}
+
+// validateURL verifies an absolute URL, empty values are skipped
+func validateURL(key, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return exception.NewErrorDescription(exception.InvalidFieldFormat,
+			fmt.Sprintf(exception.InvalidFieldFormatString, key, "an absolute URL"))
+	}
+
+	return nil
+}
+
+// validateAddress verifies a host:port network address
+func validateAddress(key, value string) error {
+	_, port, err := net.SplitHostPort(value)
+	if err == nil {
+		var p int
+		if p, err = strconv.Atoi(port); err == nil && (p <= 0 || p > 65535) {
+			err = errors.New("port out of range")
+		}
+	}
+	if err != nil {
+		return exception.NewErrorDescription(exception.InvalidFieldFormat,
+			fmt.Sprintf(exception.InvalidFieldFormatString, key, "host:port"))
+	}
+
+	return nil
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Change Configuration change notification
+type Change struct {
+	Previous *Kernel
+	Current  *Kernel
+	// Keys Changed configuration keys (e.g. alexandria.log.level), sorted
+	Keys []string
+}
+
+// Changed reports whether any key under the given prefix has changed
+func (c Change) Changed(prefix string) bool {
+	for _, k := range c.Keys {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ChangeHandler Receives configuration changes, handlers are called sequentially and must not block
+type ChangeHandler func(Change)
+
+type subscription struct {
+	prefix  string
+	handler ChangeHandler
+}
+
+// Watcher Reloads the kernel configuration when config files change (or periodically for remote
+// sources such as secret stores) and notifies subscribers, invalid configurations are discarded
+type Watcher struct {
+	loader   *Loader
+	interval time.Duration
+	onError  func(error)
+
+	mu       sync.RWMutex
+	kernel   *Kernel
+	settings map[string]interface{}
+
+	// reloadMu serializes reloads and notifications
+	reloadMu sync.Mutex
+	subsMu   sync.Mutex
+	subs     map[uint64]subscription
+	nextSub  uint64
+}
+
+// WatcherOption Watcher functional option
+type WatcherOption func(*Watcher)
+
+// WithReloadInterval reloads the configuration periodically, required by remote sources
+// without change notifications (e.g. rotated Vault secrets)
+func WithReloadInterval(interval time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.interval = interval
+	}
+}
+
+// WithReloadErrorHandler receives reload failures, previous configuration is kept
+func WithReloadErrorHandler(handler func(error)) WatcherOption {
+	return func(w *Watcher) {
+		w.onError = handler
+	}
+}
+
+// NewWatcher loads the kernel configuration and watches its config files for changes
+func NewWatcher(ctx context.Context, l *Loader, opts ...WatcherOption) (*Watcher, func(), error) {
+	w := &Watcher{
+		loader:  l,
+		onError: func(error) {},
+		subs:    make(map[uint64]subscription),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	kernel, v, err := l.load(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.kernel, w.settings = kernel, flattenSettings(v)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	fsWatcher, err := w.watchFile(watchCtx, v.ConfigFileUsed())
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	if w.interval > 0 {
+		go w.poll(watchCtx)
+	}
+
+	cleanup := func() {
+		cancel()
+		if fsWatcher != nil {
+			_ = fsWatcher.Close()
+		}
+	}
+
+	return w, cleanup, nil
+}
+
+// Kernel returns the current kernel configuration, kernels must be treated as read-only
+func (w *Watcher) Kernel() *Kernel {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.kernel
+}
+
+// Subscribe calls handler whenever a key under the given prefix changes (every change if prefix is
+// empty), returns a function to cancel the subscription
+func (w *Watcher) Subscribe(prefix string, handler ChangeHandler) func() {
+	w.subsMu.Lock()
+	defer w.subsMu.Unlock()
+
+	id := w.nextSub
+	w.nextSub++
+	w.subs[id] = subscription{
+		prefix:  prefix,
+		handler: handler,
+	}
+
+	return func() {
+		w.subsMu.Lock()
+		defer w.subsMu.Unlock()
+		delete(w.subs, id)
+	}
+}
+
+// Reload loads the configuration and notifies subscribers if any key changed, might be used to
+// trigger reloads from remote sources
+func (w *Watcher) Reload(ctx context.Context) error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	kernel, v, err := w.loader.load(ctx)
+	if err != nil {
+		return err
+	}
+	settings := flattenSettings(v)
+
+	w.mu.Lock()
+	change := Change{
+		Previous: w.kernel,
+		Current:  kernel,
+		Keys:     diffSettings(w.settings, settings),
+	}
+	if len(change.Keys) > 0 {
+		w.kernel, w.settings = kernel, settings
+	}
+	w.mu.Unlock()
+
+	if len(change.Keys) == 0 {
+		return nil
+	}
+
+	w.subsMu.Lock()
+	ids := make([]uint64, 0, len(w.subs))
+	for id := range w.subs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	subs := make([]subscription, 0, len(ids))
+	for _, id := range ids {
+		subs = append(subs, w.subs[id])
+	}
+	w.subsMu.Unlock()
+
+	for _, s := range subs {
+		if change.Changed(s.prefix) {
+			s.handler(change)
+		}
+	}
+
+	return nil
+}
+
+// watchFile reloads the configuration when the given config file or its profile files change,
+// directories are watched to support atomic writes and Kubernetes ConfigMap symlink swaps
+func (w *Watcher) watchFile(ctx context.Context, file string) (*fsnotify.Watcher, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err = fsWatcher.Add(filepath.Dir(file)); err != nil {
+		_ = fsWatcher.Close()
+		return nil, err
+	}
+
+	// Profile files share the base config name (alexandria-config.prod.yaml)
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-fsWatcher.Events:
+				if !ok {
+					return
+				}
+				base := filepath.Base(event.Name)
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 ||
+					(!strings.HasPrefix(base, name) && base != "..data") {
+					continue
+				}
+				if err := w.Reload(ctx); err != nil {
+					w.onError(err)
+				}
+			case err, ok := <-fsWatcher.Errors:
+				if !ok {
+					return
+				}
+				w.onError(err)
+			}
+		}
+	}()
+
+	return fsWatcher, nil
+}
+
+func (w *Watcher) poll(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Reload(ctx); err != nil {
+				w.onError(err)
+			}
+		}
+	}
+}
+
+func flattenSettings(v *viper.Viper) map[string]interface{} {
+	settings := make(map[string]interface{})
+	for _, k := range v.AllKeys() {
+		settings[k] = v.Get(k)
+	}
+
+	return settings
+}
+
+// diffSettings returns the sorted keys whose values differ
+func diffSettings(previous, current map[string]interface{}) []string {
+	keys := make([]string, 0)
+	for k, v := range current {
+		if p, ok := previous[k]; !ok || !reflect.DeepEqual(p, v) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range previous {
+		if _, ok := current[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
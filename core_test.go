@@ -0,0 +1,138 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexandria-oss/core/exception"
+	"github.com/stretchr/testify/assert"
+)
+
+type listAuthorsRequest struct {
+	pageToken string
+	pageSize  int32
+	sort      string
+	fields    []string
+	filter    map[string]string
+}
+
+func (r *listAuthorsRequest) GetPageToken() string {
+	return r.pageToken
+}
+
+func (r *listAuthorsRequest) GetPageSize() int32 {
+	return r.pageSize
+}
+
+func (r *listAuthorsRequest) GetSort() string {
+	return r.sort
+}
+
+func (r *listAuthorsRequest) GetFields() []string {
+	return r.fields
+}
+
+func (r *listAuthorsRequest) GetFilter() map[string]string {
+	return r.filter
+}
+
+func TestCursorEncoder(t *testing.T) {
+	encoder := NewCursorEncoder([]byte("secret"))
+	token, err := encoder.Encode(&Cursor{Keys: []interface{}{"2020-04-01", 42}, Direction: DirectionPrev})
+	assert.Nil(t, err)
+
+	// Numeric keys keep their type
+	cursor, err := encoder.Decode(token)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"2020-04-01", json.Number("42")}, cursor.Keys)
+	assert.True(t, cursor.Backward())
+
+	// Tampered and foreign tokens are rejected
+	for _, invalid := range []string{"42", token[1:], token + "a"} {
+		_, err = encoder.Decode(invalid)
+		assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+	}
+	_, err = NewCursorEncoder([]byte("other")).Decode(token)
+	assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+
+	page, err := encoder.NewPage([]string{"a", "b"}, []interface{}{"a"}, []interface{}{"b"}, false, true)
+	assert.Nil(t, err)
+	assert.Empty(t, page.PrevToken)
+	cursor, err = encoder.Decode(page.NextToken)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"b"}, cursor.Keys)
+	assert.False(t, cursor.Backward())
+}
+
+func TestNewPaginationParams(t *testing.T) {
+	params := NewPaginationParamsFromRequest(httptest.NewRequest("GET", "/v1/author?page_size=500&page_token=abc", nil))
+	assert.Equal(t, 100, params.Size)
+	assert.Equal(t, "abc", params.Token)
+
+	params = NewPaginationParamsFromProto(&listAuthorsRequest{pageToken: "abc"})
+	assert.Equal(t, 10, params.Size)
+	assert.Equal(t, "abc", params.Token)
+}
+
+func TestQuerySchema(t *testing.T) {
+	schema := QuerySchema{
+		Sortable:   []string{"created_at", "name"},
+		Selectable: []string{"id", "name", "year"},
+		Filters: map[string]FilterRule{
+			"name":       {Operators: []Operator{OpEq, OpLike}},
+			"year":       {Type: FilterInt, Operators: []Operator{OpEq, OpGte, OpIn}, Min: 1450, Max: 3000},
+			"created_at": {Type: FilterTime, Operators: []Operator{OpGt}},
+		},
+	}
+
+	params, err := schema.ParseRequest(httptest.NewRequest("GET",
+		"/v1/author?sort=-created_at,name&fields=id,name&year[gte]=2000&name=Mario&page_size=20", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, []SortField{{Field: "created_at", Descending: true}, {Field: "name"}}, params.Sort)
+	assert.Equal(t, []string{"id", "name"}, params.Fields)
+	assert.Equal(t, FilterParams{"year[gte]": "2000", "name": "Mario"}, params.Filter)
+	assert.Equal(t, 20, params.Pagination.Size)
+
+	for query, expected := range map[string]error{
+		"sort=password":                 exception.InvalidFieldFormat,
+		"fields=id,password":            exception.InvalidFieldFormat,
+		"password=root":                 exception.InvalidFieldFormat,
+		"name[gt]=Mario":                exception.InvalidFieldFormat,
+		"year=two":                      exception.InvalidFieldFormat,
+		"year[in]=2000,3000":            exception.InvalidFieldRange,
+		"created_at[gt]=yesterday":      exception.InvalidFieldFormat,
+		"created_at[gt]=2020-04-01":     nil,
+		"year[in]=1998,2003&sort=-name": nil,
+	} {
+		_, err = schema.ParseRequest(httptest.NewRequest("GET", "/v1/author?"+query, nil))
+		if expected == nil {
+			assert.Nil(t, err, query)
+			continue
+		}
+		assert.True(t, errors.Is(err, expected), query)
+	}
+
+	params, err = schema.ParseProto(&listAuthorsRequest{
+		sort:   "name",
+		fields: []string{"year"},
+		filter: map[string]string{"year[eq]": "1998"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []SortField{{Field: "name"}}, params.Sort)
+	assert.Equal(t, FilterParams{"year": "1998"}, params.Filter)
+	assert.Equal(t, 10, params.Pagination.Size)
+
+	_, err = schema.ParseProto(&listAuthorsRequest{filter: map[string]string{"year": "1000"}})
+	assert.True(t, errors.Is(err, exception.InvalidFieldRange))
+
+	// Conditions hold typed values
+	conditions, err := schema.Conditions(FilterParams{"year[in]": "1998,2003", "created_at[gt]": "2020-04-01"})
+	assert.Nil(t, err)
+	assert.Equal(t, []Condition{
+		{Field: "created_at", Operator: OpGt, Values: []interface{}{time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)}},
+		{Field: "year", Operator: OpIn, Values: []interface{}{int64(1998), int64(2003)}},
+	}, conditions)
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alexandria-oss/core/exception"
+)
+
+const (
+	// DirectionNext Cursor fetches the items following its keys
+	DirectionNext = "next"
+	// DirectionPrev Cursor fetches the items preceding its keys
+	DirectionPrev = "prev"
+)
+
+// Cursor Keyset pagination position, holds the sort-key values of the page's boundary item. Numeric
+// keys are decoded as json.Number to keep their precision
+type Cursor struct {
+	Keys      []interface{} `json:"k"`
+	Direction string        `json:"d"`
+}
+
+// Backward reports whether the cursor fetches the previous page
+func (c *Cursor) Backward() bool {
+	return c.Direction == DirectionPrev
+}
+
+// CursorEncoder Encodes cursors into opaque, HMAC-SHA256 signed base64 tokens so clients can neither
+// read nor tamper them
+type CursorEncoder struct {
+	secret []byte
+}
+
+// NewCursorEncoder creates a cursor encoder signing tokens with the given secret
+func NewCursorEncoder(secret []byte) *CursorEncoder {
+	return &CursorEncoder{secret: secret}
+}
+
+// Encode returns the cursor's token
+func (e *CursorEncoder) Encode(c *Cursor) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(e.sign(payload)), nil
+}
+
+// Decode verifies and returns the token's cursor, returns exception.InvalidFieldFormat if the token
+// is malformed or was tampered
+func (e *CursorEncoder) Decode(token string) (*Cursor, error) {
+	invalid := exception.NewErrorDescription(exception.InvalidFieldFormat,
+		fmt.Sprintf(exception.InvalidFieldFormatString, "page_token", "a valid page token"))
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, invalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, e.sign(payload)) {
+		return nil, invalid
+	}
+
+	c := new(Cursor)
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err = decoder.Decode(c); err != nil || len(c.Keys) == 0 {
+		return nil, invalid
+	}
+	switch c.Direction {
+	case DirectionNext, DirectionPrev:
+	case "":
+		c.Direction = DirectionNext
+	default:
+		return nil, invalid
+	}
+
+	return c, nil
+}
+
+// NewPage Create a page response, first and last are the sort-key values of the page's boundary
+// items. Tokens are only set if the adjacent pages exist
+func (e *CursorEncoder) NewPage(items interface{}, first, last []interface{}, hasPrev, hasNext bool) (*Page, error) {
+	page := &Page{Items: items}
+
+	var err error
+	if hasNext {
+		if page.NextToken, err = e.Encode(&Cursor{Keys: last, Direction: DirectionNext}); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if page.PrevToken, err = e.Encode(&Cursor{Keys: first, Direction: DirectionPrev}); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+func (e *CursorEncoder) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, e.secret)
+	_, _ = mac.Write(payload)
+	return mac.Sum(nil)
+}
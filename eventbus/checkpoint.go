@@ -0,0 +1,169 @@
+package eventbus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+)
+
+// Checkpoint Represents the last stream position applied by a projection
+type Checkpoint struct {
+	// Projection Projection's unique name
+	Projection string `json:"projection"`
+	// Position Amount of events applied since the last rebuild
+	Position int64 `json:"position"`
+	// EventID Last applied event ID
+	EventID string `json:"event_id"`
+	// Generation Rebuild counter, increased every time the projection is rebuilt from zero
+	Generation int `json:"generation"`
+	// UpdatedAt Checkpoint's last update timestamp
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckpointStore Persists projection's checkpoints
+type CheckpointStore interface {
+	// Load returns the projection's checkpoint, a zero-value checkpoint is returned if none was stored
+	Load(ctx context.Context, projection string) (*Checkpoint, error)
+	// Save stores the given checkpoint
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+}
+
+// MemoryCheckpointStore In-memory checkpoint store, useful for testing and non-durable projections
+type MemoryCheckpointStore struct {
+	checkpoints map[string]Checkpoint
+	mtx         *sync.RWMutex
+}
+
+// NewMemoryCheckpointStore returns a ready to use in-memory checkpoint store
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{
+		checkpoints: make(map[string]Checkpoint),
+		mtx:         new(sync.RWMutex),
+	}
+}
+
+func (s *MemoryCheckpointStore) Load(_ context.Context, projection string) (*Checkpoint, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	cp, ok := s.checkpoints[projection]
+	if !ok {
+		return &Checkpoint{Projection: projection}, nil
+	}
+
+	return &cp, nil
+}
+
+func (s *MemoryCheckpointStore) Save(_ context.Context, checkpoint *Checkpoint) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.checkpoints[checkpoint.Projection] = *checkpoint
+	return nil
+}
+
+// PostgresCheckpointStore PostgreSQL checkpoint store
+type PostgresCheckpointStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewPostgresCheckpointStore returns a PostgreSQL checkpoint store, creates the checkpoint table if
+// it does not exists
+func NewPostgresCheckpointStore(ctx context.Context, db *sql.DB, table string) (*PostgresCheckpointStore, error) {
+	if table == "" {
+		table = "projection_checkpoint"
+	}
+
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		projection VARCHAR(128) PRIMARY KEY,
+		position BIGINT NOT NULL DEFAULT 0,
+		event_id VARCHAR(128) NOT NULL DEFAULT '',
+		generation INTEGER NOT NULL DEFAULT 0,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`, table))
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresCheckpointStore{
+		db:    db,
+		table: table,
+	}, nil
+}
+
+func (s *PostgresCheckpointStore) Load(ctx context.Context, projection string) (*Checkpoint, error) {
+	cp := &Checkpoint{Projection: projection}
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT position, event_id, generation, updated_at FROM %s
+		WHERE projection = $1`, s.table), projection).Scan(&cp.Position, &cp.EventID, &cp.Generation, &cp.UpdatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return cp, nil
+}
+
+func (s *PostgresCheckpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (projection, position, event_id, generation, updated_at)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (projection) DO UPDATE SET position = EXCLUDED.position,
+		event_id = EXCLUDED.event_id, generation = EXCLUDED.generation, updated_at = EXCLUDED.updated_at`, s.table),
+		checkpoint.Projection, checkpoint.Position, checkpoint.EventID, checkpoint.Generation, checkpoint.UpdatedAt)
+	return err
+}
+
+// RedisCheckpointStore Redis checkpoint store, every checkpoint is stored as a hash
+type RedisCheckpointStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisCheckpointStore returns a Redis checkpoint store
+func NewRedisCheckpointStore(client redis.UniversalClient, prefix string) *RedisCheckpointStore {
+	if prefix == "" {
+		prefix = "alexandria:projection:"
+	}
+
+	return &RedisCheckpointStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisCheckpointStore) Load(_ context.Context, projection string) (*Checkpoint, error) {
+	fields, err := s.client.HGetAll(s.prefix + projection).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{Projection: projection}
+	if len(fields) == 0 {
+		return cp, nil
+	}
+
+	cp.EventID = fields["event_id"]
+	if cp.Position, err = strconv.ParseInt(fields["position"], 10, 64); err != nil {
+		return nil, err
+	}
+	if cp.Generation, err = strconv.Atoi(fields["generation"]); err != nil {
+		return nil, err
+	}
+	if cp.UpdatedAt, err = time.Parse(time.RFC3339Nano, fields["updated_at"]); err != nil {
+		return nil, err
+	}
+
+	return cp, nil
+}
+
+func (s *RedisCheckpointStore) Save(_ context.Context, checkpoint *Checkpoint) error {
+	return s.client.HSet(s.prefix+checkpoint.Projection,
+		"position", checkpoint.Position,
+		"event_id", checkpoint.EventID,
+		"generation", checkpoint.Generation,
+		"updated_at", checkpoint.UpdatedAt.Format(time.RFC3339Nano)).Err()
+}
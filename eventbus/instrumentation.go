@@ -0,0 +1,256 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"gocloud.dev/pubsub"
+)
+
+// ConsumerInstrumentation Required logger, metrics and tracer to instrument event consumers
+type ConsumerInstrumentation struct {
+	Logger   log.Logger
+	Tracer   stdopentracing.Tracer
+	Received metrics.Counter
+	Acked    metrics.Counter
+	Nacked   metrics.Counter
+	Duration metrics.Histogram
+	InFlight metrics.Gauge
+	// MaxInFlight Consumer's MaxHandler, used to compare against InFlight
+	MaxInFlight metrics.Gauge
+}
+
+var (
+	consumerMetricsOnce sync.Once
+	consumerMetrics     ConsumerInstrumentation
+)
+
+// NewConsumerInstrumentation returns consumer instrumentation using Prometheus metrics
+// registered into the default registry, metrics are labeled by topic
+func NewConsumerInstrumentation(logger log.Logger, tracer stdopentracing.Tracer) *ConsumerInstrumentation {
+	consumerMetricsOnce.Do(func() {
+		consumerMetrics = ConsumerInstrumentation{
+			Received: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "alexandria",
+				Subsystem: "consumer",
+				Name:      "received_total",
+				Help:      "Total of messages received by the consumer.",
+			}, []string{"topic"}),
+			Acked: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "alexandria",
+				Subsystem: "consumer",
+				Name:      "acked_total",
+				Help:      "Total of messages acknowledged by the consumer.",
+			}, []string{"topic"}),
+			Nacked: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+				Namespace: "alexandria",
+				Subsystem: "consumer",
+				Name:      "nacked_total",
+				Help:      "Total of messages negatively acknowledged by the consumer.",
+			}, []string{"topic"}),
+			Duration: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+				Namespace: "alexandria",
+				Subsystem: "consumer",
+				Name:      "handler_duration_seconds",
+				Help:      "Consumer handler duration in seconds.",
+			}, []string{"topic"}),
+			InFlight: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+				Namespace: "alexandria",
+				Subsystem: "consumer",
+				Name:      "in_flight",
+				Help:      "Current amount of running consumer handlers.",
+			}, []string{"topic"}),
+			MaxInFlight: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+				Namespace: "alexandria",
+				Subsystem: "consumer",
+				Name:      "max_in_flight",
+				Help:      "Maximum amount of concurrent consumer handlers.",
+			}, []string{"topic"}),
+		}
+	})
+
+	ins := consumerMetrics
+	ins.Logger = logger
+	ins.Tracer = tracer
+	return &ins
+}
+
+// WrapConsumerInstrumentation inject logging, metrics and tracing into the given consumer's handler
+//	* Only acknowledgements made through Request and BatchRequest Ack/Nack are counted, messages
+//	acknowledged directly (e.g. Message.Ack) are not
+func WrapConsumerInstrumentation(c *Consumer, topic string, ins *ConsumerInstrumentation) *Consumer {
+	ins.MaxInFlight.With("topic", topic).Set(float64(c.MaxHandler))
+	logger := log.With(ins.Logger, "topic", topic)
+
+	if c.Handler != nil {
+		next := c.Handler
+		c.Handler = func(r *Request) {
+			ins.Received.With("topic", topic).Add(1)
+			inFlight := ins.InFlight.With("topic", topic)
+			inFlight.Add(1)
+
+			span := startConsumerSpan(ins.Tracer, topic, r.Message)
+			r.Context = stdopentracing.ContextWithSpan(r.Context, span)
+			r.onAck = onceRecorder(ackRecorder(ins, topic, span))
+
+			defer func(begin time.Time) {
+				inFlight.Add(-1)
+				ins.Duration.With("topic", topic).Observe(time.Since(begin).Seconds())
+				span.Finish()
+				_ = level.Debug(logger).Log("msg", "called consumer", "took", time.Since(begin))
+			}(time.Now())
+			next(r)
+		}
+	}
+
+	if c.BatchHandler != nil {
+		next := c.BatchHandler
+		c.BatchHandler = func(r *BatchRequest) {
+			total := float64(len(r.Messages))
+			ins.Received.With("topic", topic).Add(total)
+			inFlight := ins.InFlight.With("topic", topic)
+			inFlight.Add(1)
+
+			span := startBatchSpan(ins.Tracer, topic, r.Messages)
+			r.Context = stdopentracing.ContextWithSpan(r.Context, span)
+			recorders := make(map[*pubsub.Message]func(bool), len(r.Messages))
+			for _, msg := range r.Messages {
+				recorders[msg] = onceRecorder(ackRecorder(ins, topic, span))
+			}
+			r.onAck = func(msg *pubsub.Message, acked bool) {
+				if recorder, ok := recorders[msg]; ok {
+					recorder(acked)
+				}
+			}
+
+			defer func(begin time.Time) {
+				inFlight.Add(-1)
+				ins.Duration.With("topic", topic).Observe(time.Since(begin).Seconds())
+				span.Finish()
+				_ = level.Debug(logger).Log("msg", "called batch consumer", "size", len(r.Messages),
+					"took", time.Since(begin))
+			}(time.Now())
+			next(r)
+		}
+	}
+
+	return c
+}
+
+// InjectTracingContext injects the current span context into the message's metadata,
+// allowing consumers to continue the trace
+func InjectTracingContext(ctx context.Context, tracer stdopentracing.Tracer, msg *pubsub.Message) error {
+	span := stdopentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+
+	if msg.Metadata == nil {
+		msg.Metadata = make(map[string]string)
+	}
+	return tracer.Inject(span.Context(), stdopentracing.TextMap, stdopentracing.TextMapCarrier(msg.Metadata))
+}
+
+// NewTracingContext returns the current span context encoded for Event's TracingContext field
+func NewTracingContext(ctx context.Context, tracer stdopentracing.Tracer) string {
+	span := stdopentracing.SpanFromContext(ctx)
+	if span == nil {
+		return ""
+	}
+
+	carrier := stdopentracing.TextMapCarrier{}
+	if err := tracer.Inject(span.Context(), stdopentracing.TextMap, carrier); err != nil {
+		return ""
+	}
+
+	tracingCtx, err := json.Marshal(carrier)
+	if err != nil {
+		return ""
+	}
+
+	return string(tracingCtx)
+}
+
+// extractTracingContext extracts the producer's span context from the message's metadata or
+// from the Event's TracingContext field
+func extractTracingContext(tracer stdopentracing.Tracer, msg *pubsub.Message) stdopentracing.SpanContext {
+	if len(msg.Metadata) > 0 {
+		if sc, err := tracer.Extract(stdopentracing.TextMap, stdopentracing.TextMapCarrier(msg.Metadata)); err == nil {
+			return sc
+		}
+	}
+
+	event, err := decodeEvent(msg)
+	if err != nil || event.TracingContext == "" {
+		return nil
+	}
+
+	carrier := stdopentracing.TextMapCarrier{}
+	if err = json.Unmarshal([]byte(event.TracingContext), &carrier); err != nil {
+		return nil
+	}
+
+	sc, err := tracer.Extract(stdopentracing.TextMap, carrier)
+	if err != nil {
+		return nil
+	}
+
+	return sc
+}
+
+func startConsumerSpan(tracer stdopentracing.Tracer, topic string, msg *pubsub.Message) stdopentracing.Span {
+	opts := []stdopentracing.StartSpanOption{ext.SpanKindConsumer}
+	if sc := extractTracingContext(tracer, msg); sc != nil {
+		opts = append(opts, stdopentracing.ChildOf(sc))
+	}
+
+	span := tracer.StartSpan(fmt.Sprintf("consume %s", topic), opts...)
+	ext.MessageBusDestination.Set(span, topic)
+	return span
+}
+
+func startBatchSpan(tracer stdopentracing.Tracer, topic string, msgs []*pubsub.Message) stdopentracing.Span {
+	opts := []stdopentracing.StartSpanOption{ext.SpanKindConsumer}
+	for _, msg := range msgs {
+		if sc := extractTracingContext(tracer, msg); sc != nil {
+			opts = append(opts, stdopentracing.FollowsFrom(sc))
+		}
+	}
+
+	span := tracer.StartSpan(fmt.Sprintf("consume batch %s", topic), opts...)
+	ext.MessageBusDestination.Set(span, topic)
+	span.SetTag("batch_size", len(msgs))
+	return span
+}
+
+// onceRecorder records only the first acknowledgement
+func onceRecorder(record func(acked bool)) func(bool) {
+	once := new(sync.Once)
+	return func(acked bool) {
+		once.Do(func() {
+			record(acked)
+		})
+	}
+}
+
+func ackRecorder(ins *ConsumerInstrumentation, topic string, span stdopentracing.Span) func(bool) {
+	return func(acked bool) {
+		if acked {
+			ins.Acked.With("topic", topic).Add(1)
+			return
+		}
+
+		ins.Nacked.With("topic", topic).Add(1)
+		ext.Error.Set(span, true)
+	}
+}
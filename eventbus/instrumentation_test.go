@@ -0,0 +1,98 @@
+package eventbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/mempubsub"
+)
+
+func TestWrapConsumerInstrumentation(t *testing.T) {
+	tracer := mocktracer.New()
+	counter := func(name string) *stdprometheus.CounterVec {
+		return stdprometheus.NewCounterVec(stdprometheus.CounterOpts{Name: name}, []string{"topic"})
+	}
+	gauge := func(name string) *stdprometheus.GaugeVec {
+		return stdprometheus.NewGaugeVec(stdprometheus.GaugeOpts{Name: name}, []string{"topic"})
+	}
+	received, acked, nacked := counter("received"), counter("acked"), counter("nacked")
+	inFlight, maxInFlight := gauge("in_flight"), gauge("max_in_flight")
+	ins := &ConsumerInstrumentation{
+		Logger:   log.NewNopLogger(),
+		Tracer:   tracer,
+		Received: kitprometheus.NewCounter(received),
+		Acked:    kitprometheus.NewCounter(acked),
+		Nacked:   kitprometheus.NewCounter(nacked),
+		Duration: kitprometheus.NewHistogram(stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Name: "duration",
+		}, []string{"topic"})),
+		InFlight:    kitprometheus.NewGauge(inFlight),
+		MaxInFlight: kitprometheus.NewGauge(maxInFlight),
+	}
+
+	// Producer side
+	producerSpan := tracer.StartSpan("publish")
+	msg := &pubsub.Message{Body: []byte("message")}
+	assert.Nil(t, InjectTracingContext(stdopentracing.ContextWithSpan(context.Background(), producerSpan),
+		tracer, msg))
+	producerSpan.Finish()
+
+	c := WrapConsumerInstrumentation(&Consumer{
+		MaxHandler: 10,
+		Handler: func(r *Request) {
+			assert.NotNil(t, stdopentracing.SpanFromContext(r.Context))
+			r.Nack()
+		},
+	}, "AUTHOR_CREATED", ins)
+	c.Handler(&Request{
+		Context: context.Background(),
+		Message: msg,
+	})
+
+	topic := "AUTHOR_CREATED"
+	assert.Equal(t, float64(1), testutil.ToFloat64(received.WithLabelValues(topic)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(acked.WithLabelValues(topic)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(nacked.WithLabelValues(topic)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(inFlight.WithLabelValues(topic)))
+	assert.Equal(t, float64(10), testutil.ToFloat64(maxInFlight.WithLabelValues(topic)))
+
+	spans := tracer.FinishedSpans()
+	assert.Len(t, spans, 2)
+	consumerSpan := spans[1]
+	assert.Equal(t, "consume AUTHOR_CREATED", consumerSpan.OperationName)
+	assert.Equal(t, producerSpan.Context().(mocktracer.MockSpanContext).SpanID, consumerSpan.ParentID)
+	assert.Equal(t, true, consumerSpan.Tag("error"))
+
+	// Batch acknowledgements are counted per message
+	ctx := context.Background()
+	memTopic := mempubsub.NewTopic()
+	defer memTopic.Shutdown(ctx)
+	sub := mempubsub.NewSubscription(memTopic, time.Minute)
+	defer sub.Shutdown(ctx)
+	batch := make([]*pubsub.Message, 0, 2)
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, memTopic.Send(ctx, &pubsub.Message{Body: []byte("message")}))
+		delivered, err := sub.Receive(ctx)
+		assert.Nil(t, err)
+		batch = append(batch, delivered)
+	}
+
+	c = WrapConsumerInstrumentation(&Consumer{
+		MaxHandler: 1,
+		BatchHandler: func(r *BatchRequest) {
+			r.Ack()
+		},
+	}, topic, ins)
+	c.BatchHandler(&BatchRequest{Context: ctx, Messages: batch})
+	assert.Equal(t, float64(2), testutil.ToFloat64(acked.WithLabelValues(topic)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(nacked.WithLabelValues(topic)))
+}
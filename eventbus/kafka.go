@@ -2,32 +2,178 @@ package eventbus
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
-	"strings"
+	"io/ioutil"
+	"net/url"
+	"regexp"
 
+	"github.com/Shopify/sarama"
+	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/exception"
 	"gocloud.dev/pubsub"
-	_ "gocloud.dev/pubsub/kafkapubsub"
+	"gocloud.dev/pubsub/kafkapubsub"
 )
 
+// kafkaNameRegex Alexandria's naming convention, upper case alphanumeric words joined by underscores or
+// hyphens, segments separated by dots (e.g. AUTHOR_CREATED, MEDIA.AUTHOR_CREATED, EXAMPLE-SERVICE)
+var kafkaNameRegex = regexp.MustCompile(`^[A-Z0-9]+([_-][A-Z0-9]+)*(\.[A-Z0-9]+([_-][A-Z0-9]+)*)*$`)
+
+// kafkaMaxNameLength Kafka's topic name length limit
+const kafkaMaxNameLength = 249
+
+// KafkaName validates the given topic or consumer group name follows Alexandria's naming convention
+// (upper case words joined by underscores or hyphens, segments separated by dots), returns
+// exception.InvalidFieldFormat if the name does not follow the convention
+//	* Names are never rewritten, lower case names are rejected
+func KafkaName(name string) error {
+	if len(name) > kafkaMaxNameLength || !kafkaNameRegex.MatchString(name) {
+		return exception.NewErrorDescription(exception.InvalidFieldFormat,
+			fmt.Sprintf(exception.InvalidFieldFormatString, name, "upper case words joined by underscores or hyphens"))
+	}
+
+	return nil
+}
+
+// NewKafkaConfig Obtain an Apache Kafka client configuration from the kernel configuration
+func NewKafkaConfig(cfg *config.Kernel) (*sarama.Config, error) {
+	kafkaCfg := kafkapubsub.MinimalConfig()
+	kafkaCfg.ClientID = cfg.EventBus.KafkaClientID
+
+	version, err := sarama.ParseKafkaVersion(cfg.EventBus.KafkaVersion)
+	if err != nil {
+		return nil, err
+	}
+	// Headers require Apache Kafka 0.11 or later
+	if version.IsAtLeast(kafkaCfg.Version) {
+		kafkaCfg.Version = version
+	}
+
+	if sasl := cfg.EventBus.KafkaSASL; sasl.Enabled {
+		kafkaCfg.Net.SASL.Enable = true
+		kafkaCfg.Net.SASL.Handshake = true
+		kafkaCfg.Net.SASL.Mechanism = sarama.SASLMechanism(sasl.Mechanism)
+		kafkaCfg.Net.SASL.User = sasl.User
+		kafkaCfg.Net.SASL.Password = sasl.Password
+		if sasl.Mechanism == sarama.SASLTypeSCRAMSHA256 || sasl.Mechanism == sarama.SASLTypeSCRAMSHA512 {
+			kafkaCfg.Net.SASL.SCRAMClientGeneratorFunc = newSCRAMClientGenerator(kafkaCfg.Net.SASL.Mechanism)
+		}
+	}
+
+	if cfg.EventBus.KafkaTLS.Enabled {
+		tlsCfg, err := newKafkaTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		kafkaCfg.Net.TLS.Enable = true
+		kafkaCfg.Net.TLS.Config = tlsCfg
+	}
+
+	kafkaCfg.Consumer.Offsets.Initial = sarama.OffsetNewest
+	if cfg.EventBus.KafkaConsumer.InitialOffset == "oldest" {
+		kafkaCfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+	if cfg.EventBus.KafkaConsumer.SessionTimeout > 0 {
+		kafkaCfg.Consumer.Group.Session.Timeout = cfg.EventBus.KafkaConsumer.SessionTimeout
+	}
+
+	return kafkaCfg, kafkaCfg.Validate()
+}
+
+// NewKafkaURLOpener Obtain an Apache Kafka URL opener (kafka://topic, kafka://group?topic=topic) from the
+// kernel configuration, might be registered into a pubsub.URLMux
+func NewKafkaURLOpener(cfg *config.Kernel) (*kafkapubsub.URLOpener, error) {
+	kafkaCfg, err := NewKafkaConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafkapubsub.URLOpener{
+		Brokers: cfg.EventBus.KafkaBrokers,
+		Config:  kafkaCfg,
+	}, nil
+}
+
 // NewKafkaConsumer Obtain a new Apache Kafka consumer (subscriber)
-// * Requires KAFKA_BROKERS OS env variable
-func NewKafkaConsumer(ctx context.Context, consumerGroup, topic string) (*pubsub.Subscription, error) {
-	consumerSub, err := pubsub.OpenSubscription(ctx, fmt.Sprintf(`kafka://%s?topic=%s`,
-		strings.ToUpper(consumerGroup), strings.ToUpper(topic)))
+func NewKafkaConsumer(ctx context.Context, cfg *config.Kernel, consumerGroup, topic string) (*pubsub.Subscription, error) {
+	opener, err := NewKafkaURLOpener(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return openKafkaConsumer(ctx, opener, consumerGroup, topic)
+}
+
+// NewKafkaProjectionOpener Obtain a projection subscription opener for the given topic, new consumer
+// groups start from the oldest offset so projections are rebuilt from zero
+func NewKafkaProjectionOpener(cfg *config.Kernel, topic string) (SubscriptionOpener, error) {
+	opener, err := NewKafkaURLOpener(cfg)
 	if err != nil {
 		return nil, err
 	}
+	opener.Config.Consumer.Offsets.Initial = sarama.OffsetOldest
 
-	return consumerSub, nil
+	return func(ctx context.Context, group string) (*pubsub.Subscription, error) {
+		return openKafkaConsumer(ctx, opener, group, topic)
+	}, nil
+}
+
+func openKafkaConsumer(ctx context.Context, opener *kafkapubsub.URLOpener, consumerGroup, topic string) (*pubsub.Subscription, error) {
+	if err := KafkaName(consumerGroup); err != nil {
+		return nil, err
+	}
+	if err := KafkaName(topic); err != nil {
+		return nil, err
+	}
+
+	return opener.OpenSubscriptionURL(ctx, &url.URL{
+		Scheme:   kafkapubsub.Scheme,
+		Host:     consumerGroup,
+		RawQuery: url.Values{"topic": []string{topic}}.Encode(),
+	})
 }
 
 // NewKafkaProducer Obtain a new Apache Kafka producer (publisher)
-// * Requires KAFKA_BROKERS OS env variable
-func NewKafkaProducer(ctx context.Context, topic string) (*pubsub.Topic, error) {
-	producer, err := pubsub.OpenTopic(ctx, fmt.Sprintf("kafka://%s", strings.ToUpper(topic)))
+func NewKafkaProducer(ctx context.Context, cfg *config.Kernel, topic string) (*pubsub.Topic, error) {
+	if err := KafkaName(topic); err != nil {
+		return nil, err
+	}
+
+	opener, err := NewKafkaURLOpener(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return producer, nil
+	return opener.OpenTopicURL(ctx, &url.URL{
+		Scheme: kafkapubsub.Scheme,
+		Host:   topic,
+	})
+}
+
+func newKafkaTLSConfig(cfg *config.Kernel) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		InsecureSkipVerify: cfg.EventBus.KafkaTLS.InsecureSkipVerify,
+	}
+
+	if cfg.EventBus.KafkaTLS.CAFile != "" {
+		ca, err := ioutil.ReadFile(cfg.EventBus.KafkaTLS.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.EventBus.KafkaTLS.CAFile)
+		}
+	}
+
+	if cfg.EventBus.KafkaTLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.EventBus.KafkaTLS.CertFile, cfg.EventBus.KafkaTLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
 }
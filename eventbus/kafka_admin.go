@@ -0,0 +1,233 @@
+package eventbus
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/alexandria-oss/core/config"
+)
+
+// KafkaTopic Apache Kafka topic settings
+type KafkaTopic struct {
+	Name              string
+	Partitions        int32
+	ReplicationFactor int16
+	// RetentionMs Message retention in milliseconds, broker's default is used if zero
+	RetentionMs int64
+}
+
+// PartitionLag Consumer group's lag of a single topic partition
+type PartitionLag struct {
+	Topic     string
+	Partition int32
+	// Committed Consumer group's committed offset, -1 if the group has not committed any offset
+	Committed int64
+	// HighWaterMark Partition's next offset to be produced
+	HighWaterMark int64
+	// Lag Messages left to be consumed by the consumer group
+	Lag int64
+}
+
+// KafkaAdmin Apache Kafka topic and consumer group administrator
+type KafkaAdmin struct {
+	client sarama.Client
+	admin  sarama.ClusterAdmin
+}
+
+// NewKafkaAdmin Obtain a new Apache Kafka cluster administrator
+func NewKafkaAdmin(cfg *config.Kernel) (*KafkaAdmin, func(), error) {
+	kafkaCfg, err := NewKafkaConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := sarama.NewClient(cfg.EventBus.KafkaBrokers, kafkaCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, nil, err
+	}
+
+	cleanup := func() {
+		// Cluster admin closes the underlying client
+		_ = admin.Close()
+	}
+
+	return &KafkaAdmin{
+		client: client,
+		admin:  admin,
+	}, cleanup, nil
+}
+
+// CreateConfigTopics creates every topic declared in the kernel configuration
+func (a *KafkaAdmin) CreateConfigTopics(cfg *config.Kernel) error {
+	topics := make([]KafkaTopic, 0, len(cfg.EventBus.KafkaTopics))
+	for _, t := range cfg.EventBus.KafkaTopics {
+		topics = append(topics, KafkaTopic{
+			Name:              t.Name,
+			Partitions:        t.Partitions,
+			ReplicationFactor: t.ReplicationFactor,
+			RetentionMs:       t.Retention.Milliseconds(),
+		})
+	}
+
+	return a.CreateTopics(topics...)
+}
+
+// CreateTopics creates the given topics, existing topics are skipped
+func (a *KafkaAdmin) CreateTopics(topics ...KafkaTopic) error {
+	existing, err := a.admin.ListTopics()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range topics {
+		name := t.Name
+		if err := KafkaName(name); err != nil {
+			return err
+		}
+		if _, ok := existing[name]; ok {
+			continue
+		}
+
+		detail := &sarama.TopicDetail{
+			NumPartitions:     t.Partitions,
+			ReplicationFactor: t.ReplicationFactor,
+			ConfigEntries:     make(map[string]*string),
+		}
+		if detail.NumPartitions <= 0 {
+			detail.NumPartitions = 1
+		}
+		if detail.ReplicationFactor <= 0 {
+			detail.ReplicationFactor = 1
+		}
+		if t.RetentionMs > 0 {
+			retention := strconv.FormatInt(t.RetentionMs, 10)
+			detail.ConfigEntries["retention.ms"] = &retention
+		}
+
+		if err = a.admin.CreateTopic(name, detail, false); err != nil {
+			return fmt.Errorf("create topic %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ConsumerGroupLag returns the consumer group's lag for every partition of the given topic
+func (a *KafkaAdmin) ConsumerGroupLag(group, topic string) ([]PartitionLag, error) {
+	if err := KafkaName(group); err != nil {
+		return nil, err
+	}
+	if err := KafkaName(topic); err != nil {
+		return nil, err
+	}
+
+	partitions, err := a.client.Partitions(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets, err := a.admin.ListConsumerGroupOffsets(group, map[string][]int32{topic: partitions})
+	if err != nil {
+		return nil, err
+	}
+
+	lags := make([]PartitionLag, 0, len(partitions))
+	for _, p := range partitions {
+		hwm, err := a.client.GetOffset(topic, p, sarama.OffsetNewest)
+		if err != nil {
+			return nil, err
+		}
+
+		lag := PartitionLag{
+			Topic:         topic,
+			Partition:     p,
+			Committed:     -1,
+			HighWaterMark: hwm,
+		}
+		if block := offsets.GetBlock(topic, p); block != nil && block.Err == sarama.ErrNoError {
+			lag.Committed = block.Offset
+		}
+
+		if lag.Committed >= 0 {
+			lag.Lag = hwm - lag.Committed
+		} else {
+			oldest, err := a.client.GetOffset(topic, p, sarama.OffsetOldest)
+			if err != nil {
+				return nil, err
+			}
+			lag.Lag = hwm - oldest
+		}
+		lags = append(lags, lag)
+	}
+
+	return lags, nil
+}
+
+// ResetOffsets sets the consumer group's offsets of every topic partition to the given position,
+// position might be sarama.OffsetOldest, sarama.OffsetNewest or a Unix timestamp in milliseconds
+//	* The consumer group must not have active members
+func (a *KafkaAdmin) ResetOffsets(group, topic string, position int64) error {
+	if err := KafkaName(group); err != nil {
+		return err
+	}
+	if err := KafkaName(topic); err != nil {
+		return err
+	}
+
+	groups, err := a.admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if len(g.Members) > 0 {
+			return fmt.Errorf("consumer group %s has %d active members", group, len(g.Members))
+		}
+	}
+
+	partitions, err := a.client.Partitions(topic)
+	if err != nil {
+		return err
+	}
+
+	// Offsets are committed directly, sarama's offset manager only moves committed offsets backwards
+	req := &sarama.OffsetCommitRequest{
+		Version:                 1,
+		ConsumerGroup:           group,
+		ConsumerGroupGeneration: sarama.GroupGenerationUndefined,
+	}
+	if retention := a.client.Config().Consumer.Offsets.Retention; retention > 0 {
+		req.Version = 2
+		req.RetentionTime = int64(retention / time.Millisecond)
+	}
+	for _, p := range partitions {
+		offset, err := a.client.GetOffset(topic, p, position)
+		if err != nil {
+			return err
+		}
+		req.AddBlock(topic, p, offset, sarama.ReceiveTime, "")
+	}
+
+	coordinator, err := a.client.Coordinator(group)
+	if err != nil {
+		return err
+	}
+	res, err := coordinator.CommitOffset(req)
+	if err != nil {
+		return err
+	}
+	for _, p := range partitions {
+		if kerr := res.Errors[topic][p]; kerr != sarama.ErrNoError {
+			return fmt.Errorf("reset offset of %s partition %d: %w", topic, p, kerr)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package eventbus
+
+import (
+	"crypto/sha512"
+	"hash"
+
+	"github.com/Shopify/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// scramSHA512 scram.HashGeneratorFcn for SCRAM-SHA-512, github.com/xdg-go/scram only ships SHA-1 and SHA-256
+var scramSHA512 scram.HashGeneratorFcn = func() hash.Hash { return sha512.New() }
+
+// scramClient sarama.SCRAMClient backed by github.com/xdg-go/scram, used by Apache Kafka SCRAM-SHA-256 and
+// SCRAM-SHA-512 mechanisms
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func newSCRAMClientGenerator(mechanism sarama.SASLMechanism) func() sarama.SCRAMClient {
+	return func() sarama.SCRAMClient {
+		if mechanism == sarama.SASLTypeSCRAMSHA512 {
+			return &scramClient{HashGeneratorFcn: scramSHA512}
+		}
+		return &scramClient{HashGeneratorFcn: scram.SHA256}
+	}
+}
+
+// Begin prepares the client for the SCRAM exchange with the server with a user name and a password
+func (c *scramClient) Begin(userName, password, authzID string) (err error) {
+	c.Client, err = c.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.ClientConversation = c.Client.NewConversation()
+	return nil
+}
+
+// Step steps client through the SCRAM exchange, it is called repeatedly until it errors or Done returns true
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.ClientConversation.Step(challenge)
+}
+
+// Done should return true when the SCRAM conversation is over
+func (c *scramClient) Done() bool {
+	return c.ClientConversation.Done()
+}
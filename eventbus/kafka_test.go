@@ -0,0 +1,139 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/exception"
+	"github.com/stretchr/testify/assert"
+	"github.com/xdg-go/scram"
+)
+
+func TestKafkaName(t *testing.T) {
+	assert.Nil(t, KafkaName("AUTHOR_CREATED"))
+	assert.Nil(t, KafkaName("MEDIA.AUTHOR-SERVICE"))
+
+	for _, invalid := range []string{"", "author_created", "Author_Created", " AUTHOR_CREATED", "AUTHOR CREATED",
+		"AUTHOR__CREATED", ".AUTHOR", "AUTHOR/CREATED"} {
+		err := KafkaName(invalid)
+		assert.True(t, errors.Is(err, exception.InvalidFieldFormat), invalid)
+	}
+}
+
+func TestNewKafkaConfig(t *testing.T) {
+	cfg, err := config.NewLoader(config.WithConfigBytes([]byte(`
+alexandria:
+  eventbus:
+    kafka:
+      brokers: ["kafka:9092"]
+      client_id: "author-service"
+      version: "2.4.0"
+      sasl:
+        enabled: true
+        mechanism: "SCRAM-SHA-512"
+        user: "alexandria"
+        password: "alexandria"
+      consumer:
+        initial_offset: "oldest"
+`))).Load(context.Background())
+	assert.Nil(t, err)
+
+	kafkaCfg, err := NewKafkaConfig(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "author-service", kafkaCfg.ClientID)
+	assert.Equal(t, sarama.V2_4_0_0, kafkaCfg.Version)
+	assert.Equal(t, sarama.OffsetOldest, kafkaCfg.Consumer.Offsets.Initial)
+	assert.True(t, kafkaCfg.Net.SASL.Enable)
+	assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA512), kafkaCfg.Net.SASL.Mechanism)
+	assert.NotNil(t, kafkaCfg.Net.SASL.SCRAMClientGeneratorFunc)
+	// Configuration must not leak into the process environment
+	assert.Empty(t, os.Getenv("KAFKA_BROKERS"))
+}
+
+func TestKafkaAdmin_ResetOffsets(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("AUTHOR_CREATED", 0, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "AUTHOR_PROJECTION", broker),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).SetVersion(1).
+			SetOffset("AUTHOR_CREATED", 0, sarama.OffsetOldest, 0).
+			SetOffset("AUTHOR_CREATED", 0, sarama.OffsetNewest, 42),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+	})
+
+	kafkaCfg := sarama.NewConfig()
+	kafkaCfg.Version = sarama.V2_4_0_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, kafkaCfg)
+	assert.Nil(t, err)
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	assert.Nil(t, err)
+	defer admin.Close()
+
+	// Target offsets are committed directly, moving forward included
+	a := &KafkaAdmin{client: client, admin: admin}
+	assert.Nil(t, a.ResetOffsets("AUTHOR_PROJECTION", "AUTHOR_CREATED", sarama.OffsetNewest))
+
+	var commit *sarama.OffsetCommitRequest
+	for _, rr := range broker.History() {
+		if req, ok := rr.Request.(*sarama.OffsetCommitRequest); ok {
+			commit = req
+		}
+	}
+	if assert.NotNil(t, commit) {
+		offset, _, err := commit.Offset("AUTHOR_CREATED", 0)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(42), offset)
+		assert.Equal(t, "AUTHOR_PROJECTION", commit.ConsumerGroup)
+	}
+
+	assert.True(t, errors.Is(a.ResetOffsets("author_projection", "AUTHOR_CREATED", sarama.OffsetNewest),
+		exception.InvalidFieldFormat))
+}
+
+func TestSCRAMClient(t *testing.T) {
+	for _, mechanism := range []sarama.SASLMechanism{sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512} {
+		hashGen := scram.SHA256
+		if mechanism == sarama.SASLTypeSCRAMSHA512 {
+			hashGen = scramSHA512
+		}
+		storedClient, err := hashGen.NewClient("user", "pencil", "")
+		assert.Nil(t, err)
+		credentials := storedClient.GetStoredCredentials(scram.KeyFactors{Salt: "QSXCR+Q6sek8bf92", Iters: 4096})
+		server, err := hashGen.NewServer(func(user string) (scram.StoredCredentials, error) {
+			if user != "user" {
+				return scram.StoredCredentials{}, errors.New("unknown user")
+			}
+			return credentials, nil
+		})
+		assert.Nil(t, err)
+
+		for _, password := range []string{"pencil", "pen"} {
+			client := newSCRAMClientGenerator(mechanism)()
+			assert.Nil(t, client.Begin("user", password, ""))
+			conversation := server.NewConversation()
+
+			challenge := ""
+			for !client.Done() {
+				msg, err := client.Step(challenge)
+				if err != nil {
+					break
+				}
+				challenge, err = conversation.Step(msg)
+				if err != nil {
+					break
+				}
+			}
+			assert.Equal(t, password == "pencil", client.Done() && conversation.Valid(), string(mechanism)+" "+password)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"gocloud.dev/pubsub"
+)
+
+// ErrProducerClosed The batch producer was already closed
+var ErrProducerClosed = errors.New("batch producer is closed")
+
+// ErrBatchAborted The message was not published because a previous message of its batch failed
+var ErrBatchAborted = errors.New("message was not published, a previous batch message failed")
+
+// BatchProducerOptions Thresholds used by BatchProducer to flush buffered messages
+type BatchProducerOptions struct {
+	// MaxBatchSize Flushes the buffer when it reaches the given amount of messages
+	MaxBatchSize int
+	// Linger Flushes the buffer when the given time has passed since the first buffered message
+	Linger time.Duration
+	// OnError Receives every failed message from an asynchronous flush, failures are logged using
+	// Logger if not set
+	OnError func(err error, msg *pubsub.Message)
+	// Logger Logs failed messages from asynchronous flushes, uses a stderr logger if not set
+	Logger log.Logger
+	// Validator Validates every message against Subject's latest schema before buffering it, Send
+	// returns validation failures. Messages are not validated if not set
+	Validator *SchemaValidator
+	// Subject Schema subject of the topic's payloads (e.g. SchemaSubject(topic))
+	Subject string
+}
+
+// BatchProducer Buffers messages and publishes them to a topic in batches, messages are published
+// one at a time in the same order they were sent
+type BatchProducer struct {
+	topic  *pubsub.Topic
+	opts   BatchProducerOptions
+	buffer []*pubsub.Message
+	timer  *time.Timer
+	// timerGen Identifies the current linger timer, stopped timers whose callback already fired must not flush
+	timerGen int
+	closed   bool
+	// last Closed once the latest queued batch is done, each batch waits for the previous one to keep
+	// messages ordered
+	last chan struct{}
+	mtx  *sync.Mutex
+}
+
+// NewBatchProducer returns a batch producer for the given topic, uses DefaultBatchSize and
+// DefaultBatchLinger if thresholds are not set
+func NewBatchProducer(topic *pubsub.Topic, opts BatchProducerOptions) *BatchProducer {
+	if opts.MaxBatchSize <= 0 {
+		opts.MaxBatchSize = DefaultBatchSize
+	}
+	if opts.Linger <= 0 {
+		opts.Linger = DefaultBatchLinger
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	}
+
+	return &BatchProducer{
+		topic:  topic,
+		opts:   opts,
+		buffer: make([]*pubsub.Message, 0, opts.MaxBatchSize),
+		mtx:    new(sync.Mutex),
+	}
+}
+
+// Send buffers the given message, the buffer is flushed asynchronously when any threshold is reached.
+// Returns ctx's error if it is already done and exception.InvalidFieldFormat if the message does not
+// match the Validator's schema
+//	* Asynchronous flushes are not bound to the given context, use Flush to publish within a context
+func (p *BatchProducer) Send(ctx context.Context, msg *pubsub.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.opts.Validator != nil {
+		if err := p.opts.Validator.ValidateMessage(ctx, p.opts.Subject, msg); err != nil {
+			return err
+		}
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.closed {
+		return ErrProducerClosed
+	}
+
+	p.buffer = append(p.buffer, msg)
+	if len(p.buffer) >= p.opts.MaxBatchSize {
+		p.flushAsync()
+		return nil
+	}
+
+	if p.timer == nil {
+		p.timerGen++
+		gen := p.timerGen
+		p.timer = time.AfterFunc(p.opts.Linger, func() {
+			p.mtx.Lock()
+			defer p.mtx.Unlock()
+			// The buffer was already taken by another flush
+			if p.timer == nil || gen != p.timerGen {
+				return
+			}
+			p.flushAsync()
+		})
+	}
+
+	return nil
+}
+
+// Flush publishes every buffered message and waits for previous batches to finish, returns a
+// *PublishError listing every failed message
+func (p *BatchProducer) Flush(ctx context.Context) error {
+	p.mtx.Lock()
+	batch := p.take()
+	prev, done := p.queue()
+	p.mtx.Unlock()
+
+	select {
+	case <-prev:
+	case <-ctx.Done():
+		// Batches must not overtake previous ones, the batch is failed instead
+		go func() {
+			<-prev
+			close(done)
+		}()
+		if len(batch) == 0 {
+			return ctx.Err()
+		}
+		return newPublishError(batch, ctx.Err())
+	}
+
+	err := p.publish(ctx, batch)
+	close(done)
+	return err
+}
+
+// Close flushes buffered messages and rejects any further message, in-flight asynchronous flushes
+// are done once Close returns
+func (p *BatchProducer) Close(ctx context.Context) error {
+	p.mtx.Lock()
+	p.closed = true
+	p.mtx.Unlock()
+
+	return p.Flush(ctx)
+}
+
+// PublishFailure Message that could not be published
+type PublishFailure struct {
+	Err     error
+	Message *pubsub.Message
+}
+
+// PublishError Every message that could not be published by a flush, in publishing order
+type PublishError struct {
+	Failures []PublishFailure
+}
+
+func newPublishError(batch []*pubsub.Message, err error) *PublishError {
+	e := &PublishError{Failures: make([]PublishFailure, 0, len(batch))}
+	for _, msg := range batch {
+		e.Failures = append(e.Failures, PublishFailure{Err: err, Message: msg})
+	}
+	return e
+}
+
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("failed to publish %d messages: %s", len(e.Failures), e.Failures[0].Err.Error())
+}
+
+// Is reports whether any failure matches target
+func (e *PublishError) Is(target error) bool {
+	for _, f := range e.Failures {
+		if errors.Is(f.Err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// take returns and clears the current buffer, must be called holding the mutex
+func (p *BatchProducer) take() []*pubsub.Message {
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+
+	batch := p.buffer
+	p.buffer = make([]*pubsub.Message, 0, p.opts.MaxBatchSize)
+	return batch
+}
+
+// queue returns the channel closed once the previous batch is done and the channel the new batch
+// must close when done, must be called holding the mutex
+func (p *BatchProducer) queue() (prev <-chan struct{}, done chan struct{}) {
+	prevDone := p.last
+	if prevDone == nil {
+		prevDone = make(chan struct{})
+		close(prevDone)
+	}
+
+	done = make(chan struct{})
+	p.last = done
+	return prevDone, done
+}
+
+// flushAsync publishes the current buffer in a new goroutine, must be called holding the mutex
+func (p *BatchProducer) flushAsync() {
+	batch := p.take()
+	if len(batch) == 0 {
+		return
+	}
+
+	prev, done := p.queue()
+	go func() {
+		defer close(done)
+		<-prev
+		p.notify(p.publish(context.Background(), batch))
+	}()
+}
+
+// publish sends the batch messages one at a time, messages after a failed one are not sent to
+// keep them ordered
+func (p *BatchProducer) publish(ctx context.Context, batch []*pubsub.Message) error {
+	for i, msg := range batch {
+		if err := p.topic.Send(ctx, msg); err != nil {
+			e := &PublishError{Failures: []PublishFailure{{Err: err, Message: msg}}}
+			for _, aborted := range batch[i+1:] {
+				e.Failures = append(e.Failures, PublishFailure{Err: ErrBatchAborted, Message: aborted})
+			}
+			return e
+		}
+	}
+
+	return nil
+}
+
+func (p *BatchProducer) notify(err error) {
+	var e *PublishError
+	if !errors.As(err, &e) {
+		return
+	}
+
+	for _, f := range e.Failures {
+		if p.opts.OnError != nil {
+			p.opts.OnError(f.Err, f.Message)
+			continue
+		}
+		_ = p.opts.Logger.Log("msg", "batch producer failed to publish message", "err", f.Err)
+	}
+}
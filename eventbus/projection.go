@@ -0,0 +1,277 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"gocloud.dev/pubsub"
+)
+
+// Projection Builds a read model from an event stream
+type Projection interface {
+	// Name projection's unique name, used as checkpoint key and consumer group. Must follow KafkaName's
+	// convention if the stream is consumed from Kafka
+	Name() string
+	// Apply applies the given message to the read model, must be idempotent
+	Apply(ctx context.Context, msg *pubsub.Message) error
+	// Reset removes every read model record, required to rebuild the projection from zero
+	Reset(ctx context.Context) error
+}
+
+// SubscriptionOpener opens a subscription using the given consumer group
+//	* To rebuild projections from zero, new consumer groups must start from the oldest offset
+type SubscriptionOpener func(ctx context.Context, group string) (*pubsub.Subscription, error)
+
+// ProjectionRunner Applies events to a projection through an eventbus.Server, tracking
+// projection's checkpoint
+type ProjectionRunner struct {
+	Projection Projection
+	Store      CheckpointStore
+	Opener     SubscriptionOpener
+	checkpoint *Checkpoint
+	// offsets Last applied Kafka offset by partition, used to skip redelivered messages
+	offsets map[int32]int64
+	mtx     *sync.Mutex
+}
+
+var (
+	projectionMetricsOnce sync.Once
+	projectionLag         metrics.Gauge
+	projectionPosition    metrics.Gauge
+	projectionEvents      metrics.Counter
+)
+
+// NewProjectionRunner returns a projection runner
+func NewProjectionRunner(p Projection, store CheckpointStore, opener SubscriptionOpener) *ProjectionRunner {
+	projectionMetricsOnce.Do(func() {
+		projectionLag = kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "alexandria",
+			Subsystem: "projection",
+			Name:      "lag_seconds",
+			Help:      "Elapsed time between event dispatching and projection apply.",
+		}, []string{"projection"})
+		projectionPosition = kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "alexandria",
+			Subsystem: "projection",
+			Name:      "checkpoint_position",
+			Help:      "Amount of events applied since the last projection rebuild.",
+		}, []string{"projection"})
+		projectionEvents = kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "alexandria",
+			Subsystem: "projection",
+			Name:      "events_total",
+			Help:      "Total of events handled by the projection.",
+		}, []string{"projection", "success"})
+	})
+
+	return &ProjectionRunner{
+		Projection: p,
+		Store:      store,
+		Opener:     opener,
+		mtx:        new(sync.Mutex),
+	}
+}
+
+// Bind loads the projection's checkpoint and attaches the projection consumer into the given server
+func (r *ProjectionRunner) Bind(ctx context.Context, s *Server) error {
+	cp, err := r.Store.Load(ctx, r.Projection.Name())
+	if err != nil {
+		return err
+	}
+
+	return r.bind(ctx, s, cp)
+}
+
+// Rebuild resets the projection's read model and checkpoint, then attaches the projection
+// consumer into the given server using a new consumer group to replay the stream from zero
+//	* Use Rebuild instead of Bind, not both
+func (r *ProjectionRunner) Rebuild(ctx context.Context, s *Server) error {
+	cp, err := r.Store.Load(ctx, r.Projection.Name())
+	if err != nil {
+		return err
+	}
+
+	if err = r.Projection.Reset(ctx); err != nil {
+		return err
+	}
+
+	cp.Generation++
+	cp.Position = 0
+	cp.EventID = ""
+	cp.UpdatedAt = time.Now().UTC()
+	if err = r.Store.Save(ctx, cp); err != nil {
+		return err
+	}
+
+	return r.bind(ctx, s, cp)
+}
+
+// Checkpoint returns a copy of the current projection's checkpoint
+func (r *ProjectionRunner) Checkpoint() Checkpoint {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.checkpoint == nil {
+		return Checkpoint{Projection: r.Projection.Name()}
+	}
+
+	return *r.checkpoint
+}
+
+func (r *ProjectionRunner) bind(ctx context.Context, s *Server, cp *Checkpoint) error {
+	sub, err := r.Opener(ctx, r.group(cp.Generation))
+	if err != nil {
+		return err
+	}
+
+	r.mtx.Lock()
+	r.checkpoint = cp
+	r.offsets = make(map[int32]int64)
+	r.mtx.Unlock()
+	projectionPosition.With("projection", r.Projection.Name()).Set(float64(cp.Position))
+
+	// Events must be applied in order, a single handler receives and applies one message at a time
+	s.AddConsumer(&Consumer{
+		MaxHandler: 1,
+		Consumer:   sub,
+		Handler:    r.handle,
+	})
+	return nil
+}
+
+func (r *ProjectionRunner) group(generation int) string {
+	if generation == 0 {
+		return r.Projection.Name()
+	}
+
+	return fmt.Sprintf("%s_%d", r.Projection.Name(), generation)
+}
+
+func (r *ProjectionRunner) handle(req *Request) {
+	name := r.Projection.Name()
+	// Redelivered messages behind the checkpoint were already applied, checkpoints never go backwards
+	partition, offset, hasOffset := kafkaPosition(req.Message)
+	r.mtx.Lock()
+	stale := hasOffset && r.isStale(partition, offset)
+	cp := *r.checkpoint
+	r.mtx.Unlock()
+	if stale {
+		req.Ack()
+		return
+	}
+
+	cp.Position++
+	event, _ := decodeEvent(req.Message)
+	if event != nil {
+		cp.EventID = event.ID
+	}
+	// A single handler is running, later messages wait until this one is applied or the consumer
+	// shuts down
+	if err := r.applyWithRetry(req.Context, req.Message, &cp); err != nil {
+		req.Nack()
+		return
+	}
+	r.mtx.Lock()
+	r.checkpoint = &cp
+	if hasOffset {
+		r.offsets[partition] = offset
+	}
+	r.mtx.Unlock()
+
+	req.Ack()
+	projectionEvents.With("projection", name, "success", "true").Add(1)
+	projectionPosition.With("projection", name).Set(float64(cp.Position))
+	if dispatched, ok := dispatchTime(req.Message, event); ok {
+		projectionLag.With("projection", name).Set(time.Since(dispatched).Seconds())
+	}
+}
+
+const (
+	projectionRetryInterval    = 100 * time.Millisecond
+	projectionMaxRetryInterval = 10 * time.Second
+)
+
+// applyWithRetry applies the message and saves the given checkpoint with exponential backoff until
+// both succeed or ctx is done, failed messages are never skipped
+func (r *ProjectionRunner) applyWithRetry(ctx context.Context, msg *pubsub.Message, cp *Checkpoint) error {
+	name := r.Projection.Name()
+	applied := false
+	interval := projectionRetryInterval
+	for {
+		var err error
+		if !applied {
+			err = r.Projection.Apply(ctx, msg)
+			applied = err == nil
+		}
+		if err == nil {
+			cp.UpdatedAt = time.Now().UTC()
+			if err = r.Store.Save(ctx, cp); err == nil {
+				return nil
+			}
+		}
+		projectionEvents.With("projection", name, "success", "false").Add(1)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > projectionMaxRetryInterval {
+			interval = projectionMaxRetryInterval
+		}
+	}
+}
+
+// isStale reports whether the given partition offset was already applied, r.mtx must be held
+func (r *ProjectionRunner) isStale(partition int32, offset int64) bool {
+	last, ok := r.offsets[partition]
+	return ok && offset <= last
+}
+
+// kafkaPosition returns the Kafka partition and offset of the given message
+func kafkaPosition(msg *pubsub.Message) (int32, int64, bool) {
+	var kafkaMsg *sarama.ConsumerMessage
+	if !msg.As(&kafkaMsg) || kafkaMsg == nil {
+		return 0, 0, false
+	}
+
+	return kafkaMsg.Partition, kafkaMsg.Offset, true
+}
+
+// decodeEvent decodes the message body as an Event, returns nil if body is not an Event
+func decodeEvent(msg *pubsub.Message) (*Event, error) {
+	event := new(Event)
+	if err := json.Unmarshal(msg.Body, event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// dispatchTime returns message's dispatching time, prefers broker timestamps over event's dispatch time
+func dispatchTime(msg *pubsub.Message, event *Event) (time.Time, bool) {
+	var kafkaMsg *sarama.ConsumerMessage
+	if msg.As(&kafkaMsg) && !kafkaMsg.Timestamp.IsZero() {
+		return kafkaMsg.Timestamp, true
+	}
+
+	if event == nil || strings.TrimSpace(event.DispatchTime) == "" {
+		return time.Time{}, false
+	}
+
+	unix, err := strconv.ParseInt(event.DispatchTime, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(unix, 0), true
+}
@@ -0,0 +1,108 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/mempubsub"
+)
+
+type counterProjection struct {
+	count  int
+	resets int
+	// fails Amount of upcoming Apply calls that fail
+	fails int
+	mtx   sync.Mutex
+}
+
+func (p *counterProjection) Name() string {
+	return "counter"
+}
+
+func (p *counterProjection) Apply(_ context.Context, _ *pubsub.Message) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.fails > 0 {
+		p.fails--
+		return errors.New("read model is not available")
+	}
+	p.count++
+	return nil
+}
+
+func (p *counterProjection) Reset(_ context.Context) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.count = 0
+	p.resets++
+	return nil
+}
+
+func TestProjectionRunner(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	topic := mempubsub.NewTopic()
+	defer topic.Shutdown(ctx)
+
+	groups := make([]string, 0)
+	opener := func(ctx context.Context, group string) (*pubsub.Subscription, error) {
+		groups = append(groups, group)
+		return mempubsub.NewSubscription(topic, time.Minute), nil
+	}
+
+	store := NewMemoryCheckpointStore()
+	// The first event is retried until applied, later events must wait for it
+	projection := &counterProjection{fails: 1}
+	runner := NewProjectionRunner(projection, store, opener)
+
+	srv := NewServer(ctx)
+	assert.Nil(t, runner.Bind(ctx, srv))
+	go srv.Serve()
+	// Wait for consumers to start
+	time.Sleep(50 * time.Millisecond)
+
+	// mempubsub does not keep ordering between pending messages, events are sent one at a time
+	var lastID string
+	for i := 1; i <= 3; i++ {
+		event := NewEvent("author", EventDomain, PriorityLow, ProviderKafka, []byte("message"))
+		lastID = event.ID
+		body, err := json.Marshal(event)
+		assert.Nil(t, err)
+		assert.Nil(t, topic.Send(ctx, &pubsub.Message{Body: body}))
+		assert.Eventually(t, func() bool {
+			return runner.Checkpoint().Position == int64(i)
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	cp, err := store.Load(ctx, projection.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), cp.Position)
+	assert.Equal(t, lastID, cp.EventID)
+	assert.Equal(t, 3, projection.count)
+
+	// Rebuild from zero
+	srvRebuild := NewServer(ctx)
+	assert.Nil(t, runner.Rebuild(ctx, srvRebuild))
+	assert.Equal(t, 1, projection.resets)
+	assert.Equal(t, 0, projection.count)
+	assert.Equal(t, []string{"counter", "counter_1"}, groups)
+
+	cp, err = store.Load(ctx, projection.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), cp.Position)
+	assert.Equal(t, 1, cp.Generation)
+
+	// Offsets behind the checkpoint are stale
+	runner.offsets[0] = 41
+	assert.True(t, runner.isStale(0, 40))
+	assert.True(t, runner.isStale(0, 41))
+	assert.False(t, runner.isStale(0, 42))
+	assert.False(t, runner.isStale(1, 0))
+}
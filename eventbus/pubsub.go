@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/alexandria-oss/core/exception"
 	"github.com/google/uuid"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -119,7 +120,7 @@ func NewEvent(serviceName, eventType, priority, provider string, content []byte)
 		Content:      content,
 		Priority:     priority,
 		Provider:     provider,
-		DispatchTime: string(time.Now().Unix()),
+		DispatchTime: strconv.FormatInt(time.Now().Unix(), 10),
 	}
 }
 
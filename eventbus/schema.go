@@ -0,0 +1,253 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexandria-oss/core/exception"
+	"github.com/go-kit/kit/log"
+	"gocloud.dev/pubsub"
+)
+
+const (
+	// SchemaJSON JSON Schema type
+	SchemaJSON = "JSON"
+	// SchemaAvro Apache Avro schema type
+	SchemaAvro = "AVRO"
+	// SchemaProtobuf Protocol Buffers schema type
+	SchemaProtobuf = "PROTOBUF"
+)
+
+// ErrIncompatibleSchema Schema is not compatible with the subject's latest version
+var ErrIncompatibleSchema = errors.New("schema is not compatible with the latest version")
+
+// Schema Represents a versioned payload schema registered under a subject
+type Schema struct {
+	// ID Registry's global schema ID
+	ID int `json:"id"`
+	// Subject Schema's subject, mostly the topic name followed by -value
+	Subject string `json:"subject"`
+	// Version Schema's version within the subject
+	Version int `json:"version"`
+	// Type Schema type (JSON, AVRO or PROTOBUF)
+	Type string `json:"schemaType"`
+	// Definition Raw schema definition
+	Definition string `json:"schema"`
+}
+
+// SchemaRegistry Stores and verifies versioned payload schemas
+type SchemaRegistry interface {
+	// GetLatestSchema returns the latest subject's schema, returns exception.EntityNotFound if
+	// the subject does not exists
+	GetLatestSchema(ctx context.Context, subject string) (*Schema, error)
+	// GetSchema returns the given subject's schema version
+	GetSchema(ctx context.Context, subject string, version int) (*Schema, error)
+	// RegisterSchema registers a new subject's schema version
+	RegisterSchema(ctx context.Context, subject string, schema *Schema) (*Schema, error)
+	// CheckCompatibility verifies the given schema against the latest subject's schema
+	CheckCompatibility(ctx context.Context, subject string, schema *Schema) (bool, error)
+}
+
+// PayloadValidatorFunc validates a payload against a schema definition
+type PayloadValidatorFunc func(definition string, payload []byte) error
+
+// SchemaSubject returns the default subject for the given topic's payloads
+func SchemaSubject(topic string) string {
+	return topic + "-value"
+}
+
+// SchemaValidator Validates event payloads using the registry's latest subject schemas
+type SchemaValidator struct {
+	Registry SchemaRegistry
+	// Validators Payload validators by schema type, replaces default validators
+	Validators map[string]PayloadValidatorFunc
+	// OnInvalid Receives every message rejected by WrapSchemaValidation before it is acknowledged (e.g.
+	// to publish it into a dead-letter topic), rejections are logged using Logger if not set
+	OnInvalid func(msg *pubsub.Message, err error)
+	// Logger Logs rejected messages and registry failures, uses a stderr logger if not set
+	Logger  log.Logger
+	schemas map[string]*Schema
+	mtx     *sync.RWMutex
+}
+
+// NewSchemaValidator returns a schema validator with the default JSON Schema, Avro and
+// Protobuf payload validators
+func NewSchemaValidator(registry SchemaRegistry) *SchemaValidator {
+	return &SchemaValidator{
+		Registry: registry,
+		Validators: map[string]PayloadValidatorFunc{
+			SchemaJSON:     ValidateJSONSchema,
+			SchemaAvro:     ValidateAvroJSON,
+			SchemaProtobuf: ValidateProtobuf,
+		},
+		Logger:  log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr)),
+		schemas: make(map[string]*Schema),
+		mtx:     new(sync.RWMutex),
+	}
+}
+
+// Validate validates the payload against the latest subject's schema
+func (v *SchemaValidator) Validate(ctx context.Context, subject string, payload []byte) error {
+	schema, err := v.latest(ctx, subject)
+	if err != nil {
+		return err
+	}
+
+	return v.validateSchema(subject, schema, payload)
+}
+
+// validateSchema validates the payload against the given subject's schema
+func (v *SchemaValidator) validateSchema(subject string, schema *Schema, payload []byte) error {
+	validate, ok := v.Validators[strings.ToUpper(schema.Type)]
+	if !ok {
+		return fmt.Errorf("schema type %s is not supported", schema.Type)
+	}
+
+	if err := validate(schema.Definition, payload); err != nil {
+		return exception.NewErrorDescription(exception.InvalidFieldFormat,
+			fmt.Sprintf("payload does not match %s schema version %d, %s", subject, schema.Version, err.Error()))
+	}
+
+	return nil
+}
+
+// ValidateMessage validates the message's payload against the latest subject's schema, Event messages
+// are validated using their content. Used by BatchProducer before publishing
+func (v *SchemaValidator) ValidateMessage(ctx context.Context, subject string, msg *pubsub.Message) error {
+	return v.Validate(ctx, subject, messagePayload(msg))
+}
+
+// Refresh removes every cached schema, latest versions will be fetched on next validation
+func (v *SchemaValidator) Refresh() {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	v.schemas = make(map[string]*Schema)
+}
+
+func (v *SchemaValidator) latest(ctx context.Context, subject string) (*Schema, error) {
+	v.mtx.RLock()
+	schema, ok := v.schemas[subject]
+	v.mtx.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err := v.Registry.GetLatestSchema(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mtx.Lock()
+	v.schemas[subject] = schema
+	v.mtx.Unlock()
+	return schema, nil
+}
+
+// WrapSchemaValidation drops every message which payload does not match the latest subject's schema,
+// Event messages are validated using their content. Batches only deliver valid messages
+//	* Invalid messages can never be processed, they are handed to OnInvalid (or logged) and acknowledged
+//	so they are neither redelivered nor block Kafka offset commits
+//	* Registry failures are retried until the handler's context is done, messages are then negatively
+//	acknowledged if the provider supports it and left unacknowledged otherwise
+func WrapSchemaValidation(c *Consumer, subject string, v *SchemaValidator) *Consumer {
+	if c.Handler != nil {
+		next := c.Handler
+		c.Handler = func(r *Request) {
+			invalid, err := v.validateWithRetry(r.Context, subject, messagePayload(r.Message))
+			if invalid {
+				v.reject(subject, r.Message, err)
+				r.Ack()
+				return
+			} else if err != nil {
+				v.log("msg", "schema registry is not available", "subject", subject, "err", err)
+				r.Nack()
+				return
+			}
+			next(r)
+		}
+	}
+
+	if c.BatchHandler != nil {
+		next := c.BatchHandler
+		c.BatchHandler = func(r *BatchRequest) {
+			valid := make([]*pubsub.Message, 0, len(r.Messages))
+			for _, msg := range r.Messages {
+				invalid, err := v.validateWithRetry(r.Context, subject, messagePayload(msg))
+				if invalid {
+					v.reject(subject, msg, err)
+					r.ack(msg)
+					continue
+				} else if err != nil {
+					v.log("msg", "schema registry is not available", "subject", subject, "err", err)
+					r.nack(msg)
+					continue
+				}
+				valid = append(valid, msg)
+			}
+			if len(valid) == 0 {
+				return
+			}
+
+			r.Messages = valid
+			next(r)
+		}
+	}
+
+	return c
+}
+
+// messagePayload returns the message's payload, Event messages return their content
+func messagePayload(msg *pubsub.Message) []byte {
+	if event, err := decodeEvent(msg); err == nil && event.ID != "" {
+		return event.Content
+	}
+
+	return msg.Body
+}
+
+func (v *SchemaValidator) reject(subject string, msg *pubsub.Message, err error) {
+	if v.OnInvalid != nil {
+		v.OnInvalid(msg, err)
+		return
+	}
+
+	v.log("msg", "dropped message not matching schema", "subject", subject, "err", err)
+}
+
+func (v *SchemaValidator) log(keyvals ...interface{}) {
+	if v.Logger != nil {
+		_ = v.Logger.Log(keyvals...)
+	}
+}
+
+const (
+	schemaRetryInterval    = 100 * time.Millisecond
+	schemaMaxRetryInterval = 10 * time.Second
+)
+
+// validateWithRetry validates the payload, fetching the latest subject's schema with exponential
+// backoff while the registry fails and ctx is not done. Reports whether the payload is invalid
+func (v *SchemaValidator) validateWithRetry(ctx context.Context, subject string, payload []byte) (bool, error) {
+	interval := schemaRetryInterval
+	for {
+		schema, err := v.latest(ctx, subject)
+		if err == nil {
+			err = v.validateSchema(subject, schema, payload)
+			return errors.Is(err, exception.InvalidFieldFormat), err
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, err
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > schemaMaxRetryInterval {
+			interval = schemaMaxRetryInterval
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexandria-oss/core/exception"
+)
+
+const schemaRegistryContentType = "application/vnd.schemaregistry.v1+json"
+
+// ConfluentRegistry Confluent-compatible schema registry REST client
+type ConfluentRegistry struct {
+	URL      string
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+// NewConfluentRegistry returns a Confluent-compatible schema registry client
+func NewConfluentRegistry(registryURL string) *ConfluentRegistry {
+	return &ConfluentRegistry{
+		URL: strings.TrimSuffix(registryURL, "/"),
+		Client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+type confluentSchemaRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+type confluentError struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (r *ConfluentRegistry) GetLatestSchema(ctx context.Context, subject string) (*Schema, error) {
+	return r.getVersion(ctx, subject, "latest")
+}
+
+func (r *ConfluentRegistry) GetSchema(ctx context.Context, subject string, version int) (*Schema, error) {
+	return r.getVersion(ctx, subject, strconv.Itoa(version))
+}
+
+func (r *ConfluentRegistry) RegisterSchema(ctx context.Context, subject string, schema *Schema) (*Schema, error) {
+	res := struct {
+		ID int `json:"id"`
+	}{}
+	err := r.do(ctx, http.MethodPost, fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject)),
+		newConfluentSchemaRequest(schema), &res)
+	if err != nil {
+		return nil, err
+	}
+
+	// The registered schema is looked up by its definition, newer versions might be registered meanwhile
+	registered := new(Schema)
+	err = r.do(ctx, http.MethodPost, fmt.Sprintf("/subjects/%s", url.PathEscape(subject)),
+		newConfluentSchemaRequest(schema), registered)
+	if err != nil {
+		return nil, err
+	}
+
+	// Avro is the registry's default type
+	schemaType := strings.ToUpper(schema.Type)
+	if schemaType == "" {
+		schemaType = SchemaAvro
+	}
+	return &Schema{
+		ID:         res.ID,
+		Subject:    subject,
+		Version:    registered.Version,
+		Type:       schemaType,
+		Definition: schema.Definition,
+	}, nil
+}
+
+func (r *ConfluentRegistry) CheckCompatibility(ctx context.Context, subject string, schema *Schema) (bool, error) {
+	res := struct {
+		IsCompatible bool `json:"is_compatible"`
+	}{}
+	err := r.do(ctx, http.MethodPost, fmt.Sprintf("/compatibility/subjects/%s/versions/latest",
+		url.PathEscape(subject)), newConfluentSchemaRequest(schema), &res)
+	if err != nil {
+		// A subject without versions is compatible with any schema
+		if errors.Is(err, exception.EntityNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	return res.IsCompatible, nil
+}
+
+func (r *ConfluentRegistry) getVersion(ctx context.Context, subject, version string) (*Schema, error) {
+	schema := new(Schema)
+	err := r.do(ctx, http.MethodGet, fmt.Sprintf("/subjects/%s/versions/%s", url.PathEscape(subject), version),
+		nil, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	// Confluent registry omits schema type for Avro schemas
+	if schema.Type == "" {
+		schema.Type = SchemaAvro
+	}
+	return schema, nil
+}
+
+func (r *ConfluentRegistry) do(ctx context.Context, method, path string, body, v interface{}) error {
+	var reqBody *bytes.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(payload)
+	} else {
+		reqBody = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, r.URL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", schemaRegistryContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", schemaRegistryContentType)
+	}
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+
+	res, err := r.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		resErr := new(confluentError)
+		_ = json.NewDecoder(res.Body).Decode(resErr)
+		switch {
+		case res.StatusCode == http.StatusNotFound:
+			return exception.NewErrorDescription(exception.EntityNotFound, resErr.Message)
+		case res.StatusCode == http.StatusConflict:
+			return exception.NewErrorDescription(ErrIncompatibleSchema, resErr.Message)
+		case res.StatusCode == http.StatusUnprocessableEntity:
+			return exception.NewErrorDescription(exception.InvalidFieldFormat, resErr.Message)
+		default:
+			return fmt.Errorf("schema registry responded with status %d: %s", res.StatusCode, resErr.Message)
+		}
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func newConfluentSchemaRequest(schema *Schema) *confluentSchemaRequest {
+	req := &confluentSchemaRequest{Schema: schema.Definition}
+	// Avro is the registry's default type
+	if t := strings.ToUpper(schema.Type); t != "" && t != SchemaAvro {
+		req.SchemaType = t
+	}
+
+	return req
+}
+
+// FileRegistry File-based schema registry, schemas are stored as <dir>/<subject>/<version>.<ext> where
+// ext is json (JSON Schema), avsc (Avro) or proto (Protobuf)
+type FileRegistry struct {
+	Dir string
+	mtx *sync.RWMutex
+}
+
+var schemaExtensions = map[string]string{
+	".json":  SchemaJSON,
+	".avsc":  SchemaAvro,
+	".proto": SchemaProtobuf,
+}
+
+// NewFileRegistry returns a file-based schema registry using the given directory
+func NewFileRegistry(dir string) *FileRegistry {
+	return &FileRegistry{
+		Dir: dir,
+		mtx: new(sync.RWMutex),
+	}
+}
+
+// validateSubject rejects subjects escaping the registry directory
+func validateSubject(subject string) error {
+	if subject == "" || subject == "." || subject == ".." || strings.ContainsAny(subject, `/\`) {
+		return exception.NewErrorDescription(exception.InvalidFieldFormat,
+			fmt.Sprintf(exception.InvalidFieldFormatString, "subject", "a name without path separators"))
+	}
+
+	return nil
+}
+
+func (r *FileRegistry) GetLatestSchema(_ context.Context, subject string) (*Schema, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	schemas, err := r.list(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return schemas[len(schemas)-1], nil
+}
+
+func (r *FileRegistry) GetSchema(_ context.Context, subject string, version int) (*Schema, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	schemas, err := r.list(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, schema := range schemas {
+		if schema.Version == version {
+			return schema, nil
+		}
+	}
+
+	return nil, exception.NewErrorDescription(exception.EntityNotFound,
+		fmt.Sprintf("schema %s version %d was not found", subject, version))
+}
+
+func (r *FileRegistry) RegisterSchema(_ context.Context, subject string, schema *Schema) (*Schema, error) {
+	if err := validateSubject(subject); err != nil {
+		return nil, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	ext := ""
+	for k, v := range schemaExtensions {
+		if v == strings.ToUpper(schema.Type) {
+			ext = k
+		}
+	}
+	if ext == "" {
+		return nil, exception.NewErrorDescription(exception.InvalidFieldFormat,
+			fmt.Sprintf(exception.InvalidFieldFormatString, "schema_type", "JSON, AVRO or PROTOBUF"))
+	}
+
+	version := 1
+	schemas, err := r.list(subject)
+	if err != nil && !errors.Is(err, exception.EntityNotFound) {
+		return nil, err
+	}
+	if len(schemas) > 0 {
+		latest := schemas[len(schemas)-1]
+		compatible, err := IsSchemaCompatible(latest, schema)
+		if err != nil {
+			return nil, err
+		}
+		if !compatible {
+			return nil, exception.NewErrorDescription(ErrIncompatibleSchema,
+				fmt.Sprintf("schema is not compatible with %s version %d", subject, latest.Version))
+		}
+		version = latest.Version + 1
+	}
+
+	dir := filepath.Join(r.Dir, subject)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, strconv.Itoa(version)+ext), []byte(schema.Definition),
+		0644); err != nil {
+		return nil, err
+	}
+
+	return &Schema{
+		ID:         version,
+		Subject:    subject,
+		Version:    version,
+		Type:       schemaExtensions[ext],
+		Definition: schema.Definition,
+	}, nil
+}
+
+func (r *FileRegistry) CheckCompatibility(ctx context.Context, subject string, schema *Schema) (bool, error) {
+	latest, err := r.GetLatestSchema(ctx, subject)
+	if err != nil {
+		if errors.Is(err, exception.EntityNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	return IsSchemaCompatible(latest, schema)
+}
+
+// list returns every subject's schema sorted by version
+func (r *FileRegistry) list(subject string) ([]*Schema, error) {
+	if err := validateSubject(subject); err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(filepath.Join(r.Dir, subject))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	schemas := make([]*Schema, 0, len(files))
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		schemaType, ok := schemaExtensions[ext]
+		if f.IsDir() || !ok {
+			continue
+		}
+
+		version, err := strconv.Atoi(strings.TrimSuffix(f.Name(), ext))
+		if err != nil {
+			continue
+		}
+
+		def, err := ioutil.ReadFile(filepath.Join(r.Dir, subject, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		schemas = append(schemas, &Schema{
+			ID:         version,
+			Subject:    subject,
+			Version:    version,
+			Type:       schemaType,
+			Definition: string(def),
+		})
+	}
+
+	if len(schemas) == 0 {
+		return nil, exception.NewErrorDescription(exception.EntityNotFound,
+			fmt.Sprintf("schema subject %s was not found", subject))
+	}
+
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Version < schemas[j].Version
+	})
+	return schemas, nil
+}
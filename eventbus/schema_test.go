@@ -0,0 +1,314 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alexandria-oss/core/exception"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/mempubsub"
+)
+
+const authorSchemaV1 = `{
+	"type": "object",
+	"properties": {
+		"author_id": {"type": "string"},
+		"name": {"type": "string", "minLength": 1}
+	},
+	"required": ["author_id", "name"]
+}`
+
+const authorSchemaV2 = `{
+	"type": "object",
+	"properties": {
+		"author_id": {"type": "string"},
+		"name": {"type": "string", "minLength": 1},
+		"country": {"type": "string"}
+	},
+	"required": ["author_id", "name"]
+}`
+
+func TestConfluentRegistry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", schemaRegistryContentType)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/subjects/AUTHOR_CREATED-value/versions/latest":
+			_ = json.NewEncoder(w).Encode(&Schema{
+				ID:         7,
+				Subject:    "AUTHOR_CREATED-value",
+				Version:    1,
+				Type:       SchemaJSON,
+				Definition: authorSchemaV1,
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/subjects/AUTHOR_CREATED-value/versions":
+			_ = json.NewEncoder(w).Encode(map[string]int{"id": 9})
+		case r.Method == http.MethodPost && r.URL.Path == "/subjects/AUTHOR_CREATED-value":
+			_ = json.NewEncoder(w).Encode(&Schema{
+				ID:         9,
+				Subject:    "AUTHOR_CREATED-value",
+				Version:    2,
+				Type:       SchemaJSON,
+				Definition: authorSchemaV2,
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/compatibility/subjects/AUTHOR_CREATED-value/versions/latest":
+			req := new(confluentSchemaRequest)
+			_ = json.NewDecoder(r.Body).Decode(req)
+			_ = json.NewEncoder(w).Encode(map[string]bool{"is_compatible": req.SchemaType == SchemaJSON})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(&confluentError{ErrorCode: 40401, Message: "Subject not found."})
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	registry := NewConfluentRegistry(srv.URL)
+	schema, err := registry.GetLatestSchema(ctx, SchemaSubject("AUTHOR_CREATED"))
+	assert.Nil(t, err)
+	assert.Equal(t, 7, schema.ID)
+	assert.Equal(t, SchemaJSON, schema.Type)
+
+	_, err = registry.GetLatestSchema(ctx, "MEDIA_CREATED-value")
+	assert.True(t, errors.Is(err, exception.EntityNotFound))
+
+	compatible, err := registry.CheckCompatibility(ctx, "AUTHOR_CREATED-value", &Schema{
+		Type:       SchemaJSON,
+		Definition: authorSchemaV2,
+	})
+	assert.Nil(t, err)
+	assert.True(t, compatible)
+
+	schema, err = registry.RegisterSchema(ctx, "AUTHOR_CREATED-value", &Schema{
+		Type:       SchemaJSON,
+		Definition: authorSchemaV2,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 9, schema.ID)
+	assert.Equal(t, 2, schema.Version)
+
+	// Producers validate messages before publishing
+	validator := NewSchemaValidator(registry)
+	event, err := json.Marshal(NewEvent("author", EventDomain, PriorityLow, ProviderKafka,
+		[]byte(`{"author_id":"1","name":"Octavio Paz"}`)))
+	assert.Nil(t, err)
+	producer := NewBatchProducer(mempubsub.NewTopic(), BatchProducerOptions{
+		Validator: validator,
+		Subject:   "AUTHOR_CREATED-value",
+	})
+	assert.Nil(t, producer.Send(ctx, &pubsub.Message{Body: event}))
+	err = producer.Send(ctx, &pubsub.Message{Body: []byte(`{"author_id":"1"}`)})
+	assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+}
+
+func TestFileRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schemas")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	registry := NewFileRegistry(dir)
+	_, err = registry.GetLatestSchema(ctx, "AUTHOR_CREATED-value")
+	assert.True(t, errors.Is(err, exception.EntityNotFound))
+
+	schema, err := registry.RegisterSchema(ctx, "AUTHOR_CREATED-value", &Schema{
+		Type:       SchemaJSON,
+		Definition: authorSchemaV1,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, schema.Version)
+
+	schema, err = registry.RegisterSchema(ctx, "AUTHOR_CREATED-value", &Schema{
+		Type:       SchemaJSON,
+		Definition: authorSchemaV2,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, schema.Version)
+
+	// Adding a required field breaks backward compatibility
+	_, err = registry.RegisterSchema(ctx, "AUTHOR_CREATED-value", &Schema{
+		Type:       SchemaJSON,
+		Definition: `{"type": "object", "required": ["author_id", "name", "email"]}`,
+	})
+	assert.True(t, errors.Is(err, ErrIncompatibleSchema))
+
+	latest, err := registry.GetLatestSchema(ctx, "AUTHOR_CREATED-value")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, latest.Version)
+
+	// Subjects must not escape the registry directory
+	_, err = registry.RegisterSchema(ctx, "../../x", &Schema{Type: SchemaJSON, Definition: authorSchemaV1})
+	assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+	_, err = registry.GetLatestSchema(ctx, "..")
+	assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+}
+
+type unavailableRegistry struct {
+	SchemaRegistry
+	failures int
+}
+
+func (r *unavailableRegistry) GetLatestSchema(ctx context.Context, subject string) (*Schema, error) {
+	if r.failures != 0 {
+		r.failures--
+		return nil, errors.New("connection refused")
+	}
+
+	return &Schema{Subject: subject, Version: 1, Type: SchemaJSON, Definition: authorSchemaV1}, nil
+}
+
+func TestWrapSchemaValidation(t *testing.T) {
+	registry := &unavailableRegistry{failures: -1}
+	handled := 0
+	rejected := make([]*pubsub.Message, 0)
+	validator := NewSchemaValidator(registry)
+	validator.Logger = log.NewNopLogger()
+	validator.OnInvalid = func(msg *pubsub.Message, err error) {
+		assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+		rejected = append(rejected, msg)
+	}
+	c := WrapSchemaValidation(&Consumer{
+		Handler: func(r *Request) {
+			handled++
+		},
+	}, "AUTHOR_CREATED-value", validator)
+
+	acks := make([]bool, 0)
+	onAck := func(acked bool) {
+		acks = append(acks, acked)
+	}
+
+	// Registry outages negatively acknowledge messages once the handler is done
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	c.Handler(&Request{Context: ctx, Message: &pubsub.Message{Body: []byte(`{"author_id":"1"}`)},
+		onAck: onAck})
+	assert.Equal(t, 0, handled)
+	assert.Equal(t, []bool{false}, acks)
+
+	// Transient failures are retried
+	registry.failures = 2
+	c.Handler(&Request{Context: context.Background(),
+		Message: &pubsub.Message{Body: []byte(`{"author_id":"1","name":"Octavio Paz"}`)}, onAck: onAck})
+	assert.Equal(t, 1, handled)
+	assert.Equal(t, []bool{false}, acks)
+
+	// Invalid messages are handed to OnInvalid and acknowledged, they would never be processed
+	bgCtx := context.Background()
+	topic := mempubsub.NewTopic()
+	defer topic.Shutdown(bgCtx)
+	sub := mempubsub.NewSubscription(topic, time.Minute)
+	defer sub.Shutdown(bgCtx)
+	assert.Nil(t, topic.Send(bgCtx, &pubsub.Message{Body: []byte(`{"author_id":"1"}`)}))
+	msg, err := sub.Receive(bgCtx)
+	assert.Nil(t, err)
+	c.Handler(&Request{Context: bgCtx, Message: msg, onAck: onAck})
+	assert.Equal(t, 1, handled)
+	assert.Equal(t, []bool{false, true}, acks)
+	assert.Equal(t, []*pubsub.Message{msg}, rejected)
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := `{
+		"definitions": {"id": {"type": "string", "format": "uuid"}},
+		"type": "object",
+		"properties": {
+			"author_id": {"$ref": "#/definitions/id"},
+			"contact": {"oneOf": [{"type": "string", "format": "email"}, {"type": "integer"}]}
+		},
+		"required": ["author_id"]
+	}`
+	assert.Nil(t, ValidateJSONSchema(schema, []byte(`{"author_id": "0b5cfb5c-5f1e-4b5e-9f0a-2c7f3b9d6a1e"}`)))
+	assert.Nil(t, ValidateJSONSchema(schema,
+		[]byte(`{"author_id": "0b5cfb5c-5f1e-4b5e-9f0a-2c7f3b9d6a1e", "contact": "octavio@example.com"}`)))
+	assert.NotNil(t, ValidateJSONSchema(schema, []byte(`{"author_id": "1"}`)))
+	assert.NotNil(t, ValidateJSONSchema(schema,
+		[]byte(`{"author_id": "0b5cfb5c-5f1e-4b5e-9f0a-2c7f3b9d6a1e", "contact": "octavio"}`)))
+	assert.NotNil(t, ValidateJSONSchema(`{"type": "object", "required": 1}`, []byte(`{}`)))
+}
+
+func TestValidateAvroJSON(t *testing.T) {
+	schema := `{
+		"type": "record",
+		"name": "Author",
+		"fields": [
+			{"name": "author_id", "type": "string"},
+			{"name": "age", "type": ["null", "int"], "default": null},
+			{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "BLOCKED"]}}
+		]
+	}`
+	assert.Nil(t, ValidateAvroJSON(schema, []byte(`{"author_id": "1", "status": "ACTIVE"}`)))
+	assert.Nil(t, ValidateAvroJSON(schema, []byte(`{"author_id": "1", "age": {"int": 30}, "status": "ACTIVE"}`)))
+	assert.NotNil(t, ValidateAvroJSON(schema, []byte(`{"author_id": "1", "status": "DELETED"}`)))
+	assert.NotNil(t, ValidateAvroJSON(schema, []byte(`{"status": "ACTIVE"}`)))
+
+	compatible, err := IsSchemaCompatible(&Schema{Type: SchemaAvro, Definition: schema}, &Schema{
+		Type:       SchemaAvro,
+		Definition: `{"type": "record", "name": "Author", "fields": [{"name": "email", "type": "string"}]}`,
+	})
+	assert.Nil(t, err)
+	assert.False(t, compatible)
+}
+
+func TestValidateProtobuf(t *testing.T) {
+	schema := `syntax = "proto3";
+message Author {
+	int32 age = 1;
+	string author_id = 2;
+	Address address = 3;
+}
+message Address {
+	string city = 1;
+}`
+	// field 1 (varint) = 150, field 2 (bytes) = "ab"
+	assert.Nil(t, ValidateProtobuf(schema, []byte{0x08, 0x96, 0x01, 0x12, 0x02, 'a', 'b'}))
+	assert.NotNil(t, ValidateProtobuf(schema, []byte{0x12, 0x05, 'a'}))
+	// field 2 declared as string but sent as varint
+	assert.NotNil(t, ValidateProtobuf(schema, []byte{0x10, 0x01}))
+	// Nested fields are verified too, field 3 holds field 1 = "a" and then field 1 = 7
+	assert.Nil(t, ValidateProtobuf(schema, []byte{0x1a, 0x03, 0x0a, 0x01, 'a'}))
+	assert.NotNil(t, ValidateProtobuf(schema, []byte{0x1a, 0x02, 0x08, 0x07}))
+	// Length overflowing int
+	assert.NotNil(t, ValidateProtobuf(schema, []byte{0x12, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80,
+		0x80, 0x01}))
+	assert.NotNil(t, ValidateProtobuf(`message Author {`, nil))
+
+	previous := &Schema{Type: SchemaProtobuf, Definition: `syntax = "proto3";
+message Author {
+	string author_id = 1;
+	int32 age = 2;
+	message Address {
+		string city = 1;
+	}
+}`}
+	compatible, err := IsSchemaCompatible(previous, &Schema{Type: SchemaProtobuf, Definition: `syntax = "proto3";
+message Author {
+	string author_id = 1;
+	int32 age = 2;
+	string country = 3;
+	message Address {
+		string city = 1;
+	}
+}`})
+	assert.Nil(t, err)
+	assert.True(t, compatible)
+
+	compatible, err = IsSchemaCompatible(previous, &Schema{Type: SchemaProtobuf, Definition: `syntax = "proto3";
+message Author {
+	string author_id = 1;
+	int32 age = 2;
+	message Address {
+		int64 city = 1;
+	}
+}`})
+	assert.Nil(t, err)
+	assert.False(t, compatible)
+}
@@ -0,0 +1,266 @@
+package eventbus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/linkedin/goavro/v2"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ValidateJSONSchema validates a JSON payload against a JSON Schema definition (draft 4, 6 or 7)
+func ValidateJSONSchema(definition string, payload []byte) error {
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(definition))
+	if err != nil {
+		return fmt.Errorf("invalid json schema, %s", err.Error())
+	}
+
+	res, err := schema.Validate(gojsonschema.NewBytesLoader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid json payload, %s", err.Error())
+	} else if res.Valid() {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(res.Errors()))
+	for _, resErr := range res.Errors() {
+		msgs = append(msgs, resErr.String())
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// ValidateAvroJSON validates a payload against an Apache Avro schema definition
+//	* Payloads must use Avro's JSON encoding, union values other than null are written as {"type": value}
+func ValidateAvroJSON(definition string, payload []byte) error {
+	codec, err := goavro.NewCodec(definition)
+	if err != nil {
+		return fmt.Errorf("invalid avro schema, %s", err.Error())
+	}
+
+	_, remaining, err := codec.NativeFromTextual(payload)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(remaining)) > 0 {
+		return errors.New("invalid avro payload, unexpected data after the encoded value")
+	}
+
+	return nil
+}
+
+// ValidateProtobuf validates a Protocol Buffers payload against the first message declared by the
+// given .proto definition, field wire types must match the declared field types
+func ValidateProtobuf(definition string, payload []byte) error {
+	file, err := parseProtobuf(definition)
+	if err != nil {
+		return err
+	}
+	if len(file.GetMessageTypes()) == 0 {
+		return errors.New("invalid protobuf schema, no message is declared")
+	}
+
+	msg := dynamic.NewMessage(file.GetMessageTypes()[0])
+	if err = msg.Unmarshal(payload); err != nil {
+		return fmt.Errorf("invalid protobuf payload, %s", err.Error())
+	}
+
+	return checkProtobufWireTypes(msg)
+}
+
+// checkProtobufWireTypes verifies no declared field was decoded as unknown, fields sent using another
+// wire type are kept as unknown fields by the decoder
+func checkProtobufWireTypes(msg *dynamic.Message) error {
+	md := msg.GetMessageDescriptor()
+	for _, number := range msg.GetUnknownFields() {
+		if md.FindFieldByNumber(number) != nil {
+			return fmt.Errorf("invalid protobuf payload, field %s.%d has an unexpected wire type",
+				md.GetFullyQualifiedName(), number)
+		}
+	}
+
+	for _, field := range md.GetFields() {
+		if field.GetMessageType() == nil || !msg.HasField(field) {
+			continue
+		}
+
+		values := make([]interface{}, 0)
+		switch v := msg.GetField(field).(type) {
+		case []interface{}:
+			values = v
+		case map[interface{}]interface{}:
+			for _, value := range v {
+				values = append(values, value)
+			}
+		default:
+			values = append(values, v)
+		}
+		for _, value := range values {
+			if nested, ok := value.(*dynamic.Message); ok {
+				if err := checkProtobufWireTypes(nested); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+const protobufSchemaFile = "schema.proto"
+
+// parseProtobuf parses a .proto definition, well-known google/protobuf imports are resolved
+func parseProtobuf(definition string) (*desc.FileDescriptor, error) {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{protobufSchemaFile: definition}),
+	}
+	files, err := parser.ParseFiles(protobufSchemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protobuf schema, %s", err.Error())
+	}
+
+	return files[0], nil
+}
+
+// IsSchemaCompatible verifies the schema is backward compatible with the previous version
+//	- JSON: new required fields must be declared by the previous version, properties keep their type
+//	- Avro: new fields must have a default value, fields keep their type
+//	- Protobuf: field numbers keep their type and label
+func IsSchemaCompatible(previous, schema *Schema) (bool, error) {
+	if !strings.EqualFold(previous.Type, schema.Type) {
+		return false, nil
+	}
+
+	switch strings.ToUpper(schema.Type) {
+	case SchemaJSON:
+		return isJSONSchemaCompatible(previous.Definition, schema.Definition)
+	case SchemaAvro:
+		return isAvroCompatible(previous.Definition, schema.Definition)
+	case SchemaProtobuf:
+		return isProtobufCompatible(previous.Definition, schema.Definition)
+	}
+
+	return false, fmt.Errorf("schema type %s is not supported", schema.Type)
+}
+
+func isJSONSchemaCompatible(previousDef, def string) (bool, error) {
+	previous, schema := make(map[string]interface{}), make(map[string]interface{})
+	if err := json.Unmarshal([]byte(previousDef), &previous); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(def), &schema); err != nil {
+		return false, err
+	}
+
+	prevProps, _ := previous["properties"].(map[string]interface{})
+	props, _ := schema["properties"].(map[string]interface{})
+	prevRequired := make(map[string]bool)
+	if required, ok := previous["required"].([]interface{}); ok {
+		for _, field := range required {
+			prevRequired[fmt.Sprint(field)] = true
+		}
+	}
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, field := range required {
+			if !prevRequired[fmt.Sprint(field)] {
+				return false, nil
+			}
+		}
+	}
+
+	for field, p := range props {
+		prevProp, ok := prevProps[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		prop, _ := p.(map[string]interface{})
+		if !reflect.DeepEqual(prevProp["type"], prop["type"]) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func isAvroCompatible(previousDef, def string) (bool, error) {
+	previous, schema := make(map[string]interface{}), make(map[string]interface{})
+	if err := json.Unmarshal([]byte(previousDef), &previous); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(def), &schema); err != nil {
+		return false, err
+	}
+
+	prevFields := make(map[string]interface{})
+	if fields, ok := previous["fields"].([]interface{}); ok {
+		for _, f := range fields {
+			field, _ := f.(map[string]interface{})
+			prevFields[fmt.Sprint(field["name"])] = field["type"]
+		}
+	}
+
+	fields, _ := schema["fields"].([]interface{})
+	for _, f := range fields {
+		field, _ := f.(map[string]interface{})
+		prevType, ok := prevFields[fmt.Sprint(field["name"])]
+		if !ok {
+			if _, hasDefault := field["default"]; !hasDefault {
+				return false, nil
+			}
+			continue
+		}
+		if !reflect.DeepEqual(prevType, field["type"]) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func isProtobufCompatible(previousDef, def string) (bool, error) {
+	previous, err := parseProtobuf(previousDef)
+	if err != nil {
+		return false, err
+	}
+	file, err := parseProtobuf(def)
+	if err != nil {
+		return false, err
+	}
+
+	prevFields := protobufFields(previous.GetMessageTypes())
+	for key, t := range protobufFields(file.GetMessageTypes()) {
+		if prevType, ok := prevFields[key]; ok && prevType != t {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// protobufFields returns every field type (including nested messages) by its message-scoped field number
+func protobufFields(messages []*desc.MessageDescriptor) map[string]string {
+	fields := make(map[string]string)
+	for _, msg := range messages {
+		for _, field := range msg.GetFields() {
+			t := field.GetLabel().String() + " " + field.GetType().String()
+			if field.GetMessageType() != nil {
+				t += " " + field.GetMessageType().GetFullyQualifiedName()
+			} else if field.GetEnumType() != nil {
+				t += " " + field.GetEnumType().GetFullyQualifiedName()
+			}
+			fields[fmt.Sprintf("%s#%d", msg.GetFullyQualifiedName(), field.GetNumber())] = t
+		}
+		for key, t := range protobufFields(msg.GetNestedMessageTypes()) {
+			fields[key] = t
+		}
+	}
+
+	return fields
+}
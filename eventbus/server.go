@@ -4,20 +4,98 @@ import (
 	"context"
 	"gocloud.dev/pubsub"
 	"sync"
+	"time"
 )
 
 type Request struct {
 	Context context.Context
 	Message *pubsub.Message
+	// onAck acknowledgement hook used by consumer instrumentation
+	onAck func(acked bool)
+}
+
+// Ack acknowledges the message, prefer it over Message.Ack to keep instrumentation accurate
+func (r *Request) Ack() {
+	r.Message.Ack()
+	if r.onAck != nil {
+		r.onAck(true)
+	}
+}
+
+// Nack negatively acknowledges the message, the message is left unacknowledged if the provider
+// does not support it
+func (r *Request) Nack() {
+	if r.Message.Nackable() {
+		r.Message.Nack()
+	}
+	if r.onAck != nil {
+		r.onAck(false)
+	}
 }
 
 type HandlerFunc func(*Request)
 
+// BatchRequest Group of received messages delivered to a BatchHandlerFunc
+type BatchRequest struct {
+	Context  context.Context
+	Messages []*pubsub.Message
+	// onAck per-message acknowledgement hook used by consumer instrumentation
+	onAck func(msg *pubsub.Message, acked bool)
+}
+
+// Ack acknowledges every message from the batch
+func (r *BatchRequest) Ack() {
+	for _, msg := range r.Messages {
+		r.ack(msg)
+	}
+}
+
+// Nack negatively acknowledges every message from the batch, messages are left unacknowledged
+// if the provider does not support it
+func (r *BatchRequest) Nack() {
+	for _, msg := range r.Messages {
+		r.nack(msg)
+	}
+}
+
+// ack acknowledges a single message from the batch
+func (r *BatchRequest) ack(msg *pubsub.Message) {
+	msg.Ack()
+	if r.onAck != nil {
+		r.onAck(msg, true)
+	}
+}
+
+// nack negatively acknowledges a single message from the batch
+func (r *BatchRequest) nack(msg *pubsub.Message) {
+	if msg.Nackable() {
+		msg.Nack()
+	}
+	if r.onAck != nil {
+		r.onAck(msg, false)
+	}
+}
+
+type BatchHandlerFunc func(*BatchRequest)
+
+const (
+	// DefaultBatchSize Default maximum amount of messages delivered to a BatchHandlerFunc
+	DefaultBatchSize = 100
+	// DefaultBatchLinger Default maximum time to wait for a batch to be filled
+	DefaultBatchLinger = 100 * time.Millisecond
+)
+
 type Consumer struct {
 	MaxHandler int
 	Consumer   *pubsub.Subscription
 	Handler    HandlerFunc
-	cancelCtx  context.CancelFunc
+	// BatchHandler Enables batch mode, replaces Handler
+	BatchHandler BatchHandlerFunc
+	// BatchSize Maximum amount of messages per batch, uses DefaultBatchSize if zero
+	BatchSize int
+	// BatchLinger Maximum time to wait for a batch to be filled, uses DefaultBatchLinger if zero
+	BatchLinger time.Duration
+	cancelCtx   context.CancelFunc
 }
 
 func (s *Consumer) serve(ctx context.Context) {
@@ -25,6 +103,11 @@ func (s *Consumer) serve(ctx context.Context) {
 		_ = s.Consumer.Shutdown(ctx)
 	}()
 
+	if s.BatchHandler != nil {
+		s.serveBatch(ctx)
+		return
+	}
+
 	// Loop on received messages. We can use a channel as a semaphore to limit how
 	// many goroutines we have active at a time as well as wait on the goroutines
 	// to finish before exiting.
@@ -53,7 +136,8 @@ recvLoop:
 
 			// Do work based on the message
 			// When operation is successful, must acknowledge message
-			ctxHdl, _ := context.WithCancel(ctx)
+			ctxHdl, cancel := context.WithCancel(ctx)
+			defer cancel()
 			s.Handler(&Request{
 				Context: ctxHdl,
 				Message: msg,
@@ -68,6 +152,95 @@ recvLoop:
 	}
 }
 
+func (s *Consumer) serveBatch(ctx context.Context) {
+	size := s.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	linger := s.BatchLinger
+	if linger <= 0 {
+		linger = DefaultBatchLinger
+	}
+
+	// Receive messages in a separate goroutine, so batches can be dispatched
+	// when linger time is reached
+	msgs := make(chan *pubsub.Message)
+	go func() {
+		defer close(msgs)
+		for {
+			msg, err := s.Consumer.Receive(ctx)
+			if err != nil {
+				// Errors from Receive indicate that Receive will no longer succeed.
+				s.cancelCtx()
+				return
+			}
+
+			select {
+			case msgs <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	sem := make(chan struct{}, s.MaxHandler)
+	dispatch := func(batch []*pubsub.Message) {
+		// Acquire the semaphore even if the context is canceled, received messages
+		// must be handled before shutting down
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem }() // Release the semaphore.
+
+			ctxHdl, cancel := context.WithCancel(ctx)
+			defer cancel()
+			s.BatchHandler(&BatchRequest{
+				Context:  ctxHdl,
+				Messages: batch,
+			})
+		}()
+	}
+
+	batch := make([]*pubsub.Message, 0, size)
+	timer := time.NewTimer(linger)
+	defer timer.Stop()
+recvLoop:
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				break recvLoop
+			}
+
+			batch = append(batch, msg)
+			if len(batch) < size {
+				continue
+			}
+		case <-timer.C:
+		}
+
+		if len(batch) > 0 {
+			dispatch(batch)
+			batch = make([]*pubsub.Message, 0, size)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(linger)
+	}
+
+	if len(batch) > 0 {
+		dispatch(batch)
+	}
+
+	// Wait to finish handling any unacknowledged batches by totally acquiring the semaphore.
+	for n := 0; n < s.MaxHandler; n++ {
+		sem <- struct{}{}
+	}
+}
+
 type Server struct {
 	Consumers   []*Consumer
 	rootContext context.Context
@@ -0,0 +1,131 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/mempubsub"
+)
+
+func TestBatchConsumer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	topic := mempubsub.NewTopic()
+	defer topic.Shutdown(ctx)
+	sub := mempubsub.NewSubscription(topic, time.Minute)
+
+	mtx := new(sync.Mutex)
+	batches := make([]int, 0)
+	srv := NewServer(ctx, &Consumer{
+		MaxHandler: 2,
+		Consumer:   sub,
+		BatchHandler: func(r *BatchRequest) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			batches = append(batches, len(r.Messages))
+			r.Ack()
+		},
+		BatchSize:   5,
+		BatchLinger: 50 * time.Millisecond,
+	})
+	go srv.Serve()
+
+	producer := NewBatchProducer(topic, BatchProducerOptions{
+		MaxBatchSize: 4,
+		Linger:       20 * time.Millisecond,
+		OnError: func(err error, _ *pubsub.Message) {
+			t.Error(err)
+		},
+	})
+	for i := 0; i < 12; i++ {
+		assert.Nil(t, producer.Send(ctx, &pubsub.Message{Body: []byte("message")}))
+	}
+	assert.Nil(t, producer.Close(ctx))
+	assert.Equal(t, ErrProducerClosed, producer.Send(ctx, &pubsub.Message{Body: []byte("message")}))
+
+	assert.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		total := 0
+		for _, size := range batches {
+			assert.LessOrEqual(t, size, 5)
+			total += size
+		}
+		return total == 12
+	}, time.Second, 10*time.Millisecond)
+}
+
+type recordLogger struct {
+	mtx  sync.Mutex
+	logs [][]interface{}
+}
+
+func (l *recordLogger) Log(keyvals ...interface{}) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.logs = append(l.logs, keyvals)
+	return nil
+}
+
+func (l *recordLogger) len() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return len(l.logs)
+}
+
+func TestBatchProducer_Close(t *testing.T) {
+	ctx := context.Background()
+
+	// Linger flushes racing with Close must be done before Close returns, publishing into the shutdown
+	// topic would fail otherwise
+	for i := 0; i < 50; i++ {
+		topic := mempubsub.NewTopic()
+		logger := new(recordLogger)
+		producer := NewBatchProducer(topic, BatchProducerOptions{
+			MaxBatchSize: 10,
+			Linger:       time.Millisecond,
+			Logger:       logger,
+		})
+		assert.Nil(t, producer.Send(ctx, &pubsub.Message{Body: []byte("message")}))
+		time.Sleep(time.Duration(i%3) * time.Millisecond)
+		assert.Nil(t, producer.Close(ctx))
+		assert.Nil(t, topic.Shutdown(ctx))
+		time.Sleep(2 * time.Millisecond)
+		assert.Equal(t, 0, logger.len())
+	}
+
+	// Asynchronous failures are logged if OnError is not set
+	topic := mempubsub.NewTopic()
+	assert.Nil(t, topic.Shutdown(ctx))
+	logger := new(recordLogger)
+	producer := NewBatchProducer(topic, BatchProducerOptions{MaxBatchSize: 1, Logger: logger})
+	assert.Nil(t, producer.Send(ctx, &pubsub.Message{Body: []byte("message")}))
+	assert.Nil(t, producer.Close(ctx))
+	assert.Equal(t, 1, logger.len())
+
+	// Flush reports every failed message, messages after a failure are not sent to keep them ordered
+	producer = NewBatchProducer(topic, BatchProducerOptions{MaxBatchSize: 10, Linger: time.Minute})
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, producer.Send(ctx, &pubsub.Message{Body: []byte("message")}))
+	}
+	err := producer.Flush(ctx)
+	var publishErr *PublishError
+	if assert.True(t, errors.As(err, &publishErr)) {
+		assert.Len(t, publishErr.Failures, 3)
+		assert.NotEqual(t, ErrBatchAborted, publishErr.Failures[0].Err)
+		assert.Equal(t, ErrBatchAborted, publishErr.Failures[2].Err)
+	}
+	assert.True(t, errors.Is(err, ErrBatchAborted))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	producer = NewBatchProducer(mempubsub.NewTopic(), BatchProducerOptions{})
+	assert.Equal(t, context.Canceled, producer.Send(cancelled, &pubsub.Message{Body: []byte("message")}))
+}
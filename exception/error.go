@@ -28,3 +28,18 @@ var EntitiesNotFound = errors.New("resources not found")
 
 // EntityExists Entity was already created
 var EntityExists = errors.New("resource already exists")
+
+// Unauthenticated Request credentials are missing or invalid
+var Unauthenticated = errors.New("missing or invalid credentials")
+
+// Forbidden Authenticated identity is not allowed to perform the request
+var Forbidden = errors.New("permission denied")
+
+// RateLimited Too many requests were sent
+var RateLimited = errors.New("rate limit exceeded")
+
+// Unavailable Service is temporarily unable to handle requests (e.g. open circuit breaker or full bulkhead)
+var Unavailable = errors.New("service unavailable")
+
+// Timeout Request was not handled within the deadline
+var Timeout = errors.New("request timed out")
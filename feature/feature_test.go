@@ -0,0 +1,162 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexandria-oss/core/auth"
+	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/exception"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlag_IsEnabled(t *testing.T) {
+	admin := &auth.IdentityClaims{Username: "octavio", Role: auth.RoleAdmin}
+	user := &auth.IdentityClaims{Username: "aruiz", Role: auth.RoleUser}
+
+	flag := &Flag{Name: "new_search", Enabled: true}
+	assert.True(t, flag.IsEnabled(nil))
+	assert.True(t, flag.IsEnabled(user))
+
+	flag.Roles = []string{auth.RoleAdmin}
+	assert.True(t, flag.IsEnabled(admin))
+	assert.False(t, flag.IsEnabled(user))
+	assert.False(t, flag.IsEnabled(nil))
+
+	flag.Users = []string{"aruiz"}
+	assert.True(t, flag.IsEnabled(user))
+
+	flag.Enabled = false
+	assert.False(t, flag.IsEnabled(admin))
+
+	// Rollout buckets are consistent and close to the given percentage
+	percentage := 30
+	rollout := &Flag{Name: "new_search", Enabled: true, Percentage: &percentage}
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		claims := &auth.IdentityClaims{Username: fmt.Sprintf("user_%d", i)}
+		if rollout.IsEnabled(claims) {
+			enabled++
+		}
+		assert.Equal(t, rollout.IsEnabled(claims), rollout.IsEnabled(claims))
+	}
+	assert.InDelta(t, 300, enabled, 60)
+	assert.False(t, rollout.IsEnabled(nil))
+
+	// A 0% rollout enables the feature for nobody
+	percentage = 0
+	assert.False(t, rollout.IsEnabled(user))
+	percentage = 100
+	assert.True(t, rollout.IsEnabled(nil))
+}
+
+func TestConfigStore(t *testing.T) {
+	cfg, err := config.NewLoader(config.WithConfigBytes([]byte(`
+alexandria:
+  features:
+    flags:
+      - name: "new_search"
+        enabled: true
+        roles: ["ROLE_ADMIN"]
+      - name: "dark_mode"
+        enabled: true
+        percentage: 50
+`))).Load(context.Background())
+	assert.Nil(t, err)
+
+	store, cleanup, err := NewStore(cfg)
+	assert.Nil(t, err)
+	defer cleanup()
+
+	ctx := context.Background()
+	flags := NewFlags(store)
+	assert.True(t, flags.IsEnabled(ctx, "new_search", &auth.IdentityClaims{Role: auth.RoleAdmin}))
+	assert.False(t, flags.IsEnabled(ctx, "new_search", &auth.IdentityClaims{Role: auth.RoleUser}))
+	assert.False(t, flags.IsEnabled(ctx, "unknown", nil))
+
+	list, err := store.List(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "dark_mode", list[0].Name)
+	assert.Equal(t, 50, *list[0].Percentage)
+
+	_, err = store.Get(ctx, "unknown")
+	assert.True(t, errors.Is(err, exception.EntityNotFound))
+}
+
+func TestFileStoreHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flags")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store := NewFileStore(filepath.Join(dir, "flags.json"))
+	newRouter := func(h *Handler) *mux.Router {
+		r := mux.NewRouter()
+		h.SetRoutes(r.PathPrefix("/v1").Subrouter(), r.PathPrefix("/v1/private").Subrouter(),
+			r.PathPrefix("/v1/admin").Subrouter())
+		return r
+	}
+	put := func(r *mux.Router, name, body, token string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/v1/admin/features/"+name, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	sign := func(role, secret string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.IdentityClaims{
+			Username: "octavio",
+			Role:     role,
+		}).SignedString([]byte(secret))
+		assert.Nil(t, err)
+		return token
+	}
+
+	// Writes are disabled by default
+	r := newRouter(NewHandler(store))
+	assert.Equal(t, http.StatusMethodNotAllowed, put(r, "new_search", `{"enabled": true}`, ""))
+
+	r = newRouter(NewWritableHandler(store, "secret"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/features/new_search", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Writes require an administrator signed token
+	assert.Equal(t, http.StatusUnauthorized, put(r, "new_search", `{"enabled": true}`, ""))
+	assert.Equal(t, http.StatusUnauthorized, put(r, "new_search", `{"enabled": true}`,
+		sign(auth.RoleAdmin, "forged")))
+	assert.Equal(t, http.StatusForbidden, put(r, "new_search", `{"enabled": true}`,
+		sign(auth.RoleUser, "secret")))
+
+	// Flip flag
+	admin := sign(auth.RoleAdmin, "secret")
+	assert.Equal(t, http.StatusOK, put(r, "new_search", `{"enabled": true, "percentage": 10}`, admin))
+	assert.Equal(t, http.StatusBadRequest, put(r, "dark_mode", `{"enabled": true, "percentage": 150}`, admin))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/features", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	flags := make([]*Flag, 0)
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&flags))
+	assert.Len(t, flags, 1)
+	assert.Equal(t, "new_search", flags[0].Name)
+	assert.Equal(t, 10, *flags[0].Percentage)
+
+	// Flags are persisted
+	flag, err := NewFileStore(filepath.Join(dir, "flags.json")).Get(context.Background(), "new_search")
+	assert.Nil(t, err)
+	assert.True(t, flag.Enabled)
+}
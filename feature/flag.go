@@ -0,0 +1,109 @@
+package feature
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/alexandria-oss/core/auth"
+	"github.com/alexandria-oss/core/config"
+)
+
+// Flag Feature flag, users and roles are targeted before the percentage rollout
+type Flag struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Percentage Rollout percentage, users are bucketed consistently by username. 0 enables the
+	// feature for nobody, nil (no rollout) or 100 enable the feature for every user
+	Percentage *int `json:"percentage,omitempty"`
+	// Users Usernames the feature is restricted to
+	Users []string `json:"users,omitempty"`
+	// Roles Roles the feature is restricted to (e.g. ROLE_ADMIN)
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Store Feature flag storage
+type Store interface {
+	// Get returns the given flag, returns exception.EntityNotFound if the flag does not exist
+	Get(ctx context.Context, name string) (*Flag, error)
+	// List returns every flag sorted by name
+	List(ctx context.Context) ([]*Flag, error)
+	// Set creates or replaces the given flag
+	Set(ctx context.Context, flag *Flag) error
+}
+
+// IsEnabled reports whether the flag is enabled for the given identity, claims might be nil for
+// anonymous requests
+func (f *Flag) IsEnabled(claims *auth.IdentityClaims) bool {
+	if !f.Enabled {
+		return false
+	}
+
+	if len(f.Users) > 0 || len(f.Roles) > 0 {
+		// Targeted identities are not affected by the rollout percentage
+		return claims != nil && (contains(f.Users, claims.Username) || contains(f.Roles, claims.Role))
+	}
+
+	if f.Percentage == nil || *f.Percentage >= 100 {
+		return true
+	}
+	if *f.Percentage <= 0 || claims == nil || claims.Username == "" {
+		return false
+	}
+
+	return bucket(f.Name, claims.Username) < uint32(*f.Percentage)
+}
+
+// Flags Evaluates feature flags from a store
+type Flags struct {
+	store Store
+}
+
+// NewFlags returns a feature flag evaluator
+func NewFlags(store Store) *Flags {
+	return &Flags{store: store}
+}
+
+// IsEnabled reports whether the given flag is enabled for the given identity, unknown flags and
+// store failures disable the feature
+func (f *Flags) IsEnabled(ctx context.Context, name string, claims *auth.IdentityClaims) bool {
+	flag, err := f.store.Get(ctx, name)
+	if err != nil {
+		return false
+	}
+
+	return flag.IsEnabled(claims)
+}
+
+// Store returns the underlying flag store
+func (f *Flags) Store() Store {
+	return f.store
+}
+
+// NewStore Obtain the feature flag store set in the kernel configuration (config, file or redis)
+func NewStore(cfg *config.Kernel) (Store, func(), error) {
+	switch cfg.Features.Store {
+	case "file":
+		return NewFileStore(cfg.Features.File), func() {}, nil
+	case "redis":
+		return newRedisStoreFromConfig(cfg)
+	default:
+		return NewConfigStore(cfg), func() {}, nil
+	}
+}
+
+// bucket returns the user's rollout bucket [0, 100) for the given flag
+func bucket(flag, username string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(flag + ":" + username))
+	return h.Sum32() % 100
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
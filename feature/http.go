@@ -0,0 +1,95 @@
+package feature
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/alexandria-oss/core/auth"
+	"github.com/alexandria-oss/core/exception"
+	"github.com/alexandria-oss/core/httputil"
+	"github.com/gorilla/mux"
+)
+
+// Handler Feature flag admin HTTP handler, serves /v1/admin/features to inspect flags and, if
+// writes are enabled, flip them
+type Handler struct {
+	store Store
+	// secret JWT signing secret, writes are disabled if empty
+	secret string
+}
+
+// NewHandler returns a read-only feature flag admin HTTP handler, implements proxy.Handler
+func NewHandler(store Store) *Handler {
+	return &Handler{store: store}
+}
+
+// NewWritableHandler returns a feature flag admin HTTP handler which also flips flags through
+// PUT /v1/admin/features/{name}, implements proxy.Handler
+//	* Writes require a bearer JWT signed with the given secret holding an administrator role
+func NewWritableHandler(store Store, secret string) *Handler {
+	return &Handler{store: store, secret: secret}
+}
+
+func (h *Handler) SetRoutes(_, _, admin *mux.Router) {
+	admin.Path("/features").Methods(http.MethodGet).HandlerFunc(h.list)
+	admin.Path("/features/{name}").Methods(http.MethodGet).HandlerFunc(h.get)
+	if h.secret != "" {
+		admin.Path("/features/{name}").Methods(http.MethodPut).HandlerFunc(h.set)
+	}
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	flags, err := h.store.List(r.Context())
+	if err != nil {
+		httputil.ResponseErrJSON(r.Context(), err, w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, flags)
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	flag, err := h.store.Get(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		httputil.ResponseErrJSON(r.Context(), err, w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, flag)
+}
+
+func (h *Handler) set(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.VerifyBearerJWT(r.Header.Get("Authorization"), h.secret)
+	if err != nil {
+		httputil.ResponseErrJSON(r.Context(), err, w)
+		return
+	}
+	if claims.Role != auth.RoleAdmin && claims.Role != auth.RoleRoot {
+		httputil.ResponseErrJSON(r.Context(), exception.NewErrorDescription(exception.Forbidden,
+			"an administrator role is required to update feature flags"), w)
+		return
+	}
+
+	flag := new(Flag)
+	if err := json.NewDecoder(r.Body).Decode(flag); err != nil {
+		httputil.ResponseErrJSON(r.Context(), exception.NewErrorDescription(exception.InvalidFieldFormat,
+			fmt.Sprintf(exception.InvalidFieldFormatString, "body", "a JSON feature flag")), w)
+		return
+	}
+	// Path takes precedence over body
+	flag.Name = mux.Vars(r)["name"]
+
+	if err := h.store.Set(r.Context(), flag); err != nil {
+		httputil.ResponseErrJSON(r.Context(), err, w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, flag)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,293 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/exception"
+	"github.com/alexandria-oss/core/persistence"
+	"github.com/go-redis/redis/v7"
+)
+
+// ConfigStore In-memory flag store loaded from the kernel configuration (alexandria.features.flags),
+// flags set at runtime are kept until the next configuration reload
+type ConfigStore struct {
+	flags map[string]*Flag
+	mu    sync.RWMutex
+}
+
+// NewConfigStore returns a flag store holding the kernel configuration flags
+func NewConfigStore(cfg *config.Kernel) *ConfigStore {
+	s := new(ConfigStore)
+	s.Reload(cfg)
+	return s
+}
+
+// Reload replaces every flag with the kernel configuration flags
+func (s *ConfigStore) Reload(cfg *config.Kernel) {
+	flags := make(map[string]*Flag, len(cfg.Features.Flags))
+	for _, f := range cfg.Features.Flags {
+		flags[f.Name] = &Flag{
+			Name:       f.Name,
+			Enabled:    f.Enabled,
+			Percentage: f.Percentage,
+			Users:      f.Users,
+			Roles:      f.Roles,
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags = flags
+}
+
+// WatchConfigStore reloads the given store whenever alexandria.features changes, returns a function
+// to stop watching
+func WatchConfigStore(w *config.Watcher, s *ConfigStore) func() {
+	return w.Subscribe("alexandria.features", func(c config.Change) {
+		s.Reload(c.Current)
+	})
+}
+
+func (s *ConfigStore) Get(_ context.Context, name string) (*Flag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flag, ok := s.flags[name]
+	if !ok {
+		return nil, flagNotFound(name)
+	}
+
+	f := *flag
+	return &f, nil
+}
+
+func (s *ConfigStore) List(_ context.Context) ([]*Flag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flags := make([]*Flag, 0, len(s.flags))
+	for _, flag := range s.flags {
+		f := *flag
+		flags = append(flags, &f)
+	}
+
+	return sortFlags(flags), nil
+}
+
+func (s *ConfigStore) Set(_ context.Context, flag *Flag) error {
+	if err := validateFlag(flag); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := *flag
+	s.flags[flag.Name] = &f
+	return nil
+}
+
+// FileStore Flag store backed by a JSON file holding an array of flags, the file is read on every
+// call so external changes are picked up immediately
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore returns a JSON file flag store, a missing file holds no flags
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (s *FileStore) Get(_ context.Context, name string) (*Flag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flags, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range flags {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+
+	return nil, flagNotFound(name)
+}
+
+func (s *FileStore) List(_ context.Context) ([]*Flag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flags, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return sortFlags(flags), nil
+}
+
+func (s *FileStore) Set(_ context.Context, flag *Flag) error {
+	if err := validateFlag(flag); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flags, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	f := *flag
+	replaced := false
+	for i := range flags {
+		if flags[i].Name == flag.Name {
+			flags[i], replaced = &f, true
+		}
+	}
+	if !replaced {
+		flags = append(flags, &f)
+	}
+
+	data, err := json.MarshalIndent(sortFlags(flags), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write atomically to avoid partial reads from other processes
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s *FileStore) read() ([]*Flag, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make([]*Flag, 0), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	flags := make([]*Flag, 0)
+	if err = json.Unmarshal(data, &flags); err != nil {
+		return nil, fmt.Errorf("flags file %s: %w", s.path, err)
+	}
+
+	return flags, nil
+}
+
+// RedisStore Flag store backed by a Redis hash (flag name -> JSON flag), shared by every replica
+type RedisStore struct {
+	client redis.UniversalClient
+	key    string
+}
+
+// NewRedisStore returns a Redis flag store using the given hash key
+func NewRedisStore(client redis.UniversalClient, key string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		key:    key,
+	}
+}
+
+func newRedisStoreFromConfig(cfg *config.Kernel) (Store, func(), error) {
+	client, cleanup, err := persistence.NewRedisPool(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return NewRedisStore(client, cfg.Features.RedisKey), cleanup, nil
+}
+
+func (s *RedisStore) Get(_ context.Context, name string) (*Flag, error) {
+	data, err := s.client.HGet(s.key, name).Bytes()
+	if err == redis.Nil {
+		return nil, flagNotFound(name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	flag := new(Flag)
+	if err = json.Unmarshal(data, flag); err != nil {
+		return nil, err
+	}
+
+	return flag, nil
+}
+
+func (s *RedisStore) List(_ context.Context) ([]*Flag, error) {
+	values, err := s.client.HGetAll(s.key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make([]*Flag, 0, len(values))
+	for _, v := range values {
+		flag := new(Flag)
+		if err = json.Unmarshal([]byte(v), flag); err != nil {
+			return nil, err
+		}
+		flags = append(flags, flag)
+	}
+
+	return sortFlags(flags), nil
+}
+
+func (s *RedisStore) Set(_ context.Context, flag *Flag) error {
+	if err := validateFlag(flag); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(flag)
+	if err != nil {
+		return err
+	}
+
+	return s.client.HSet(s.key, flag.Name, data).Err()
+}
+
+func validateFlag(flag *Flag) error {
+	if flag.Name == "" {
+		return exception.NewErrorDescription(exception.RequiredField, fmt.Sprintf(exception.RequiredFieldString, "name"))
+	}
+	if flag.Percentage != nil && (*flag.Percentage < 0 || *flag.Percentage > 100) {
+		return exception.NewErrorDescription(exception.InvalidFieldRange,
+			fmt.Sprintf(exception.InvalidFieldRangeString, "percentage", "0", "101"))
+	}
+
+	return nil
+}
+
+func flagNotFound(name string) error {
+	return exception.NewErrorDescription(exception.EntityNotFound, fmt.Sprintf("feature flag %s not found", name))
+}
+
+func sortFlags(flags []*Flag) []*Flag {
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+	return flags
+}
@@ -3,11 +3,19 @@ module github.com/alexandria-oss/core
 go 1.13
 
 require (
+	github.com/Shopify/sarama v1.26.1
+	github.com/alicebob/miniredis/v2 v2.11.4
+	github.com/aws/aws-sdk-go v1.27.0
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/go-kit/kit v0.10.0
 	github.com/go-redis/redis/v7 v7.2.0
 	github.com/google/uuid v1.1.1
 	github.com/gorilla/mux v1.7.3
+	github.com/jhump/protoreflect v1.6.0
+	github.com/lib/pq v1.1.1
+	github.com/linkedin/goavro/v2 v2.9.8
+	github.com/mitchellh/mapstructure v1.1.2
 	github.com/opentracing/opentracing-go v1.1.0
 	github.com/openzipkin-contrib/zipkin-go-opentracing v0.4.5
 	github.com/openzipkin/zipkin-go v0.2.2
@@ -17,10 +25,15 @@ require (
 	github.com/sony/sonyflake v1.0.0
 	github.com/spf13/viper v1.6.3
 	github.com/stretchr/testify v1.5.1
+	github.com/vmihailenco/msgpack/v4 v4.3.11
+	github.com/xdg-go/scram v1.0.2
+	github.com/xeipuuv/gojsonschema v1.2.0
 	go.uber.org/zap v1.13.0
 	gocloud.dev v0.19.0
 	gocloud.dev/pubsub/kafkapubsub v0.19.0
 	golang.org/x/crypto v0.0.0-20200206161412-a0c6ece9d31a
+	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
 	golang.org/x/time v0.0.0-20191024005414-555d28b269f0
 	google.golang.org/grpc v1.27.1
+	gopkg.in/yaml.v2 v2.2.8
 )
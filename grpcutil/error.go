@@ -25,6 +25,16 @@ func ErrorToCode(err error) codes.Code {
 		return codes.OutOfRange
 	case errors.Is(err, exception.EntityExists):
 		return codes.AlreadyExists
+	case errors.Is(err, exception.Unauthenticated):
+		return codes.Unauthenticated
+	case errors.Is(err, exception.Forbidden):
+		return codes.PermissionDenied
+	case errors.Is(err, exception.RateLimited):
+		return codes.ResourceExhausted
+	case errors.Is(err, exception.Unavailable):
+		return codes.Unavailable
+	case errors.Is(err, exception.Timeout):
+		return codes.DeadlineExceeded
 	default:
 		return codes.Internal
 	}
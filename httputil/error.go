@@ -44,6 +44,16 @@ func ErrorToCode(err error) int {
 		return http.StatusBadRequest
 	case errors.Is(err, exception.EntityExists):
 		return http.StatusConflict
+	case errors.Is(err, exception.Unauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, exception.Forbidden):
+		return http.StatusForbidden
+	case errors.Is(err, exception.RateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, exception.Unavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, exception.Timeout):
+		return http.StatusGatewayTimeout
 	default:
 		return http.StatusInternalServerError
 	}
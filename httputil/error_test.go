@@ -16,6 +16,12 @@ func TestErrorToCode(t *testing.T) {
 		fmt.Sprintf(exception.RequiredFieldString, "test"))
 	assert.Equal(t, 400, ErrorToCode(err))
 
+	err = exception.NewErrorDescription(exception.Unauthenticated, "token signature is invalid")
+	assert.Equal(t, 401, ErrorToCode(err))
+
+	err = exception.NewErrorDescription(exception.Unavailable, "circuit breaker is open")
+	assert.Equal(t, 503, ErrorToCode(err))
+
 	err = errors.New("custom error")
 	assert.Equal(t, 500, ErrorToCode(err))
 }
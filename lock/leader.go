@@ -0,0 +1,57 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// Elector Elects a single leader among replicas campaigning for the same key, used to run singleton
+// workers (e.g. outbox relays or scheduled jobs) on one replica at a time
+type Elector struct {
+	locker *Locker
+	key    string
+	leader int32
+}
+
+// NewElector creates an elector campaigning for the given key
+func NewElector(locker *Locker, key string) *Elector {
+	return &Elector{
+		locker: locker,
+		key:    key,
+	}
+}
+
+// IsLeader reports whether this replica currently leads
+func (e *Elector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+// Run campaigns until ctx is done, running fn every time leadership is won. fn's context is cancelled
+// once leadership is lost, returning from fn steps down and campaigns again. Returns ctx's error or
+// the first error returned by fn
+func (e *Elector) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	for {
+		lock, err := e.locker.Acquire(ctx, e.key)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		atomic.StoreInt32(&e.leader, 1)
+		err = fn(lock.Context())
+		atomic.StoreInt32(&e.leader, 0)
+		errRelease := lock.Release()
+
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil && !errors.Is(err, context.Canceled):
+			return err
+		case errRelease != nil && !errors.Is(errRelease, ErrLockLost):
+			return errRelease
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/persistence"
+	"github.com/go-redis/redis/v7"
+	"github.com/google/uuid"
+)
+
+// ErrNotAcquired The lock is held by another owner
+var ErrNotAcquired = errors.New("lock not acquired")
+
+// ErrLockLost The lock expired or was taken over before being released
+var ErrLockLost = errors.New("lock lost")
+
+var (
+	// renewScript extends the lease only if the lock is still owned
+	renewScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	// releaseScript deletes the lock only if it is still owned
+	releaseScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Locker Distributed lock manager following the Redlock algorithm, locks are acquired once a majority
+// of the independent Redis nodes grant them. A single client (e.g. from persistence.NewRedisPool) is
+// enough if losing locks on Redis failover is acceptable
+type Locker struct {
+	clients []redis.UniversalClient
+	prefix  string
+	ttl     time.Duration
+	retry   time.Duration
+	drift   float64
+}
+
+// Option Sets a locker option
+type Option func(*Locker)
+
+// WithPrefix sets the keys prefix, lock: by default
+func WithPrefix(prefix string) Option {
+	return func(l *Locker) {
+		l.prefix = prefix
+	}
+}
+
+// WithTTL sets the lock lease, renewed every third of it while held. 10 seconds by default
+func WithTTL(ttl time.Duration) Option {
+	return func(l *Locker) {
+		l.ttl = ttl
+	}
+}
+
+// WithRetryDelay sets the delay between Acquire attempts, 100 milliseconds by default
+func WithRetryDelay(delay time.Duration) Option {
+	return func(l *Locker) {
+		l.retry = delay
+	}
+}
+
+// NewLocker creates a locker on top of the given independent Redis nodes
+func NewLocker(clients []redis.UniversalClient, opts ...Option) *Locker {
+	l := &Locker{
+		clients: clients,
+		prefix:  "lock:",
+		ttl:     10 * time.Second,
+		retry:   100 * time.Millisecond,
+		drift:   0.01,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// NewRedisLocker creates a locker on top of the configured Redis deployment
+func NewRedisLocker(cfg *config.Kernel, opts ...Option) (*Locker, func(), error) {
+	client, cleanup, err := persistence.NewRedisPool(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return NewLocker([]redis.UniversalClient{client}, opts...), cleanup, nil
+}
+
+// Lock Held distributed lock, the lease is renewed until released or lost
+type Lock struct {
+	locker *Locker
+	key    string
+	value  string
+	token  int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+	err    error
+}
+
+// Acquire blocks until the lock is acquired or ctx is done. The lock is released once ctx is done
+func (l *Locker) Acquire(ctx context.Context, key string) (*Lock, error) {
+	for {
+		lock, err := l.TryAcquire(ctx, key)
+		if err == nil || !errors.Is(err, ErrNotAcquired) {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(l.retry):
+		}
+	}
+}
+
+// TryAcquire acquires the lock once, returns ErrNotAcquired if held by another owner
+func (l *Locker) TryAcquire(ctx context.Context, key string) (*Lock, error) {
+	value := uuid.New().String()
+	start := time.Now()
+
+	acquired := 0
+	var token int64
+	var lastErr error
+	for _, c := range l.clients {
+		ok, err := c.SetNX(l.prefix+key, value, l.ttl).Result()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !ok {
+			continue
+		}
+		acquired++
+
+		// Fencing tokens increase on every acquisition, storage rejects writes carrying older tokens
+		fence, err := c.Incr(l.prefix + key + ":fence").Result()
+		if err == nil && fence > token {
+			token = fence
+		}
+	}
+
+	validity := l.ttl - time.Since(start) - time.Duration(float64(l.ttl)*l.drift)
+	if acquired < l.quorum() || validity <= 0 {
+		l.release(l.prefix+key, value)
+		if lastErr != nil && acquired == 0 {
+			return nil, lastErr
+		}
+		return nil, ErrNotAcquired
+	}
+
+	lockCtx, cancel := context.WithCancel(ctx)
+	lock := &Lock{
+		locker: l,
+		key:    l.prefix + key,
+		value:  value,
+		token:  token,
+		ctx:    lockCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go lock.renew()
+
+	return lock, nil
+}
+
+func (l *Locker) quorum() int {
+	return len(l.clients)/2 + 1
+}
+
+// release deletes the owned lock from every node, returns how many nodes held it
+func (l *Locker) release(key, value string) int {
+	released := 0
+	for _, c := range l.clients {
+		if n, err := releaseScript.Run(c, []string{key}, value).Int(); err == nil && n > 0 {
+			released++
+		}
+	}
+
+	return released
+}
+
+// Token returns the lock's fencing token
+func (lk *Lock) Token() int64 {
+	return lk.token
+}
+
+// Context returns a context cancelled once the lock is released or lost
+func (lk *Lock) Context() context.Context {
+	return lk.ctx
+}
+
+// Err returns ErrLockLost once the lease could not be renewed
+func (lk *Lock) Err() error {
+	select {
+	case <-lk.done:
+		return lk.err
+	default:
+		return nil
+	}
+}
+
+// Release releases the lock, returns ErrLockLost if it was lost before
+func (lk *Lock) Release() error {
+	if !lk.stop(nil) {
+		return lk.err
+	}
+
+	if lk.locker.release(lk.key, lk.value) < lk.locker.quorum() {
+		return ErrLockLost
+	}
+
+	return nil
+}
+
+// stop ends the lease renewal, returns false if already stopped
+func (lk *Lock) stop(err error) bool {
+	stopped := false
+	lk.once.Do(func() {
+		lk.err = err
+		close(lk.done)
+		lk.cancel()
+		stopped = true
+	})
+
+	return stopped
+}
+
+func (lk *Lock) renew() {
+	ticker := time.NewTicker(lk.locker.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lk.done:
+			return
+		case <-lk.ctx.Done():
+			// Parent context cancelled, the lock is released
+			_ = lk.Release()
+			return
+		case <-ticker.C:
+			renewed := 0
+			for _, c := range lk.locker.clients {
+				n, err := renewScript.Run(c, []string{lk.key}, lk.value, lk.locker.ttl.Milliseconds()).Int()
+				if err == nil && n > 0 {
+					renewed++
+				}
+			}
+			if renewed < lk.locker.quorum() {
+				lk.stop(ErrLockLost)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLocker(t *testing.T, nodes int) (*Locker, []*miniredis.Miniredis) {
+	servers := make([]*miniredis.Miniredis, 0, nodes)
+	clients := make([]redis.UniversalClient, 0, nodes)
+	for i := 0; i < nodes; i++ {
+		s, err := miniredis.Run()
+		assert.Nil(t, err)
+		servers = append(servers, s)
+		clients = append(clients, redis.NewClient(&redis.Options{Addr: s.Addr()}))
+	}
+
+	return NewLocker(clients, WithTTL(300*time.Millisecond), WithRetryDelay(10*time.Millisecond)), servers
+}
+
+func TestLocker(t *testing.T) {
+	locker, servers := newTestLocker(t, 3)
+	for _, s := range servers {
+		defer s.Close()
+	}
+	ctx := context.Background()
+
+	lock, err := locker.TryAcquire(ctx, "outbox")
+	assert.Nil(t, err)
+	_, err = locker.TryAcquire(ctx, "outbox")
+	assert.True(t, errors.Is(err, ErrNotAcquired))
+
+	// Leases are renewed while held
+	time.Sleep(400 * time.Millisecond)
+	assert.Nil(t, lock.Err())
+	_, err = locker.TryAcquire(ctx, "outbox")
+	assert.True(t, errors.Is(err, ErrNotAcquired))
+
+	assert.Nil(t, lock.Release())
+	assert.NotNil(t, lock.Context().Err())
+
+	// Fencing tokens increase on every acquisition
+	next, err := locker.Acquire(ctx, "outbox")
+	assert.Nil(t, err)
+	assert.Greater(t, next.Token(), lock.Token())
+
+	// A minority of failing nodes keeps the lock, losing the majority cancels it
+	servers[0].Del("lock:outbox")
+	time.Sleep(150 * time.Millisecond)
+	assert.Nil(t, next.Err())
+	servers[1].Del("lock:outbox")
+	select {
+	case <-next.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("lost lock context was not cancelled")
+	}
+	assert.Equal(t, ErrLockLost, next.Err())
+	assert.Equal(t, ErrLockLost, next.Release())
+
+	// Cancelled acquisitions give up
+	held, err := locker.Acquire(ctx, "relay")
+	assert.Nil(t, err)
+	defer held.Release()
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = locker.Acquire(timeout, "relay")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestElector(t *testing.T) {
+	locker, servers := newTestLocker(t, 1)
+	defer servers[0].Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var leaders, runs int32
+	worker := func(ctx context.Context) error {
+		assert.Equal(t, int32(1), atomic.AddInt32(&leaders, 1))
+		atomic.AddInt32(&runs, 1)
+		select {
+		case <-ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+		atomic.AddInt32(&leaders, -1)
+		return nil
+	}
+
+	electors := []*Elector{NewElector(locker, "relay"), NewElector(locker, "relay")}
+	errs := make(chan error, len(electors))
+	for _, e := range electors {
+		go func(e *Elector) {
+			errs <- e.Run(ctx, worker)
+		}(e)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	assert.NotEqual(t, electors[0].IsLeader(), electors[1].IsLeader())
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	for range electors {
+		assert.Equal(t, context.Canceled, <-errs)
+	}
+	assert.True(t, atomic.LoadInt32(&runs) >= 2)
+}
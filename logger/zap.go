@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"github.com/alexandria-oss/core/config"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	logZap "github.com/go-kit/kit/log/zap"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -14,3 +16,72 @@ func NewZapLogger() log.Logger {
 
 	return logZap.NewZapSugarLogger(loggerZap, level)
 }
+
+// NewZapLeveledLogger Obtain a zap logger filtered by the given level, level might be updated at runtime
+func NewZapLeveledLogger(level zap.AtomicLevel) (log.Logger, error) {
+	cfg := zap.NewProductionConfig()
+	cfg.Level = level
+	loggerZap, err := cfg.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return newZapLeveledLogger(loggerZap), nil
+}
+
+// zapLeveledLogger Emits go-kit log lines at the zap level matching their level key, lines without
+// level are emitted at info level
+type zapLeveledLogger struct {
+	loggers map[string]log.Logger
+}
+
+func newZapLeveledLogger(loggerZap *zap.Logger) *zapLeveledLogger {
+	// Skip zapLeveledLogger.Log frame to report the caller
+	loggerZap = loggerZap.WithOptions(zap.AddCallerSkip(1))
+	return &zapLeveledLogger{
+		loggers: map[string]log.Logger{
+			level.DebugValue().String(): logZap.NewZapSugarLogger(loggerZap, zapcore.DebugLevel),
+			level.InfoValue().String():  logZap.NewZapSugarLogger(loggerZap, zapcore.InfoLevel),
+			level.WarnValue().String():  logZap.NewZapSugarLogger(loggerZap, zapcore.WarnLevel),
+			level.ErrorValue().String(): logZap.NewZapSugarLogger(loggerZap, zapcore.ErrorLevel),
+		},
+	}
+}
+
+func (l *zapLeveledLogger) Log(keyvals ...interface{}) error {
+	logger := l.loggers[level.InfoValue().String()]
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		if keyvals[i] != level.Key() {
+			continue
+		}
+		if value, ok := keyvals[i+1].(level.Value); ok {
+			if leveled, ok := l.loggers[value.String()]; ok {
+				logger = leveled
+			}
+			// zap encodes its own level
+			keyvals = append(keyvals[:i:i], keyvals[i+2:]...)
+		}
+		break
+	}
+
+	return logger.Log(keyvals...)
+}
+
+// NewLevel Obtain a runtime-updatable log level from the kernel configuration
+func NewLevel(cfg *config.Kernel) (zap.AtomicLevel, error) {
+	level := zap.NewAtomicLevel()
+	if err := level.UnmarshalText([]byte(cfg.Logging.Level)); err != nil {
+		return level, err
+	}
+
+	return level, nil
+}
+
+// WatchLevel updates the given level whenever alexandria.log.level changes, returns a function to
+// stop watching
+func WatchLevel(w *config.Watcher, level zap.AtomicLevel) func() {
+	return w.Subscribe("alexandria.log.level", func(c config.Change) {
+		// Configuration was validated, level is always valid
+		_ = level.UnmarshalText([]byte(c.Current.Logging.Level))
+	})
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLeveledLogger(t *testing.T) {
+	atom := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	core, logs := observer.New(atom)
+	logger := log.With(newZapLeveledLogger(zap.New(core)), "service", "author")
+
+	_ = level.Debug(logger).Log("msg", "debug")
+	_ = level.Warn(logger).Log("msg", "warn")
+	_ = logger.Log("msg", "no level")
+	entries := logs.TakeAll()
+	assert.Len(t, entries, 3)
+	assert.Equal(t, zapcore.DebugLevel, entries[0].Level)
+	assert.Equal(t, zapcore.WarnLevel, entries[1].Level)
+	assert.Equal(t, zapcore.InfoLevel, entries[2].Level)
+	assert.Equal(t, map[string]interface{}{"service": "author", "msg": "debug"}, entries[0].ContextMap())
+
+	// Raising the level drops lower lines only
+	atom.SetLevel(zapcore.WarnLevel)
+	_ = level.Info(logger).Log("msg", "info")
+	_ = level.Error(logger).Log("msg", "error")
+	entries = logs.TakeAll()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/exception"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWrapResiliencyPolicy(t *testing.T) {
+	ctx := context.Background()
+	policy := config.ResiliencyPolicy{
+		Timeout:            20 * time.Millisecond,
+		Idempotent:         true,
+		Retries:            2,
+		RetryBackoff:       time.Millisecond,
+		RetryMaxBackoff:    5 * time.Millisecond,
+		BreakerMaxRequests: 1,
+		BreakerTimeout:     time.Minute,
+		BreakerFailures:    10,
+	}
+
+	// Server-side errors are retried, client-side errors are not
+	var attempts int32
+	e := WrapResiliencyPolicy(func(ctx context.Context, request interface{}) (interface{}, error) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return nil, errors.New("connection reset")
+		}
+		return "ok", nil
+	}, "author", "get", policy, nil)
+	res, err := e(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", res)
+	assert.Equal(t, int32(3), attempts)
+
+	attempts = 0
+	e = WrapResiliencyPolicy(func(ctx context.Context, request interface{}) (interface{}, error) {
+		atomic.AddInt32(&attempts, 1)
+		return nil, exception.EntityNotFound
+	}, "author", "list", policy, nil)
+	_, err = e(ctx, nil)
+	assert.True(t, errors.Is(err, exception.EntityNotFound))
+	assert.Equal(t, int32(1), attempts)
+
+	// Every attempt gets its own timeout
+	attempts = 0
+	e = WrapResiliencyPolicy(func(ctx context.Context, request interface{}) (interface{}, error) {
+		atomic.AddInt32(&attempts, 1)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, "author", "search", policy, nil)
+	_, err = e(ctx, nil)
+	assert.True(t, errors.Is(err, exception.Timeout))
+	assert.Equal(t, int32(3), attempts)
+
+	// Non-idempotent endpoints are never retried
+	attempts = 0
+	policy.Idempotent = false
+	e = WrapResiliencyPolicy(func(ctx context.Context, request interface{}) (interface{}, error) {
+		atomic.AddInt32(&attempts, 1)
+		return nil, errors.New("connection reset")
+	}, "author", "create", policy, nil)
+	_, err = e(ctx, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), attempts)
+}
+
+func TestBulkheadMiddleware(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	e := BulkheadMiddleware(1)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		started <- struct{}{}
+		<-release
+		return nil, nil
+	})
+
+	done := make(chan error)
+	go func() {
+		_, err := e(context.Background(), nil)
+		done <- err
+	}()
+	<-started
+
+	_, err := e(context.Background(), nil)
+	assert.True(t, errors.Is(err, exception.Unavailable))
+	close(release)
+	assert.Nil(t, <-done)
+
+	go func() {
+		<-started
+	}()
+	_, err = e(context.Background(), nil)
+	assert.Nil(t, err)
+}
+
+type logRecorder struct {
+	logs [][]interface{}
+}
+
+func (l *logRecorder) Log(keyvals ...interface{}) error {
+	l.logs = append(l.logs, keyvals)
+	return nil
+}
+
+func TestNewResiliencyBreaker(t *testing.T) {
+	logger := new(logRecorder)
+	policy := config.ResiliencyPolicy{
+		BreakerMaxRequests: 1,
+		BreakerTimeout:     time.Minute,
+		BreakerFailures:    2,
+	}
+	e := WrapResiliencyPolicy(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return nil, errors.New("connection refused")
+	}, "media", "upload", policy, log.Logger(logger))
+
+	for i := 0; i < 2; i++ {
+		_, err := e(context.Background(), nil)
+		assert.False(t, errors.Is(err, exception.Unavailable))
+	}
+	_, err := e(context.Background(), nil)
+	assert.True(t, errors.Is(err, exception.Unavailable))
+	assert.Equal(t, "circuit breaker media.upload is open", exception.GetErrorDescription(err))
+
+	assert.Len(t, logger.logs, 1)
+	assert.Contains(t, logger.logs[0], "media.upload")
+	assert.Contains(t, logger.logs[0], "open")
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.Nil(t, err)
+	values := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			if len(m.GetLabel()) == 0 || m.GetLabel()[0].GetValue() != "media.upload" {
+				continue
+			}
+			if m.GetGauge() != nil {
+				values[f.GetName()] = m.GetGauge().GetValue()
+			} else {
+				values[f.GetName()] += m.GetCounter().GetValue()
+			}
+		}
+	}
+	assert.Equal(t, float64(2), values["alexandria_resiliency_breaker_state"])
+	assert.Equal(t, float64(1), values["alexandria_resiliency_breaker_transitions_total"])
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/exception"
+	"github.com/alexandria-oss/core/httputil"
+	"github.com/go-kit/kit/circuitbreaker"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/sony/gobreaker"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	breakerMetricsOnce sync.Once
+	breakerState       metrics.Gauge
+	breakerTransitions metrics.Counter
+)
+
+// TimeoutMiddleware returns an endpoint middleware that cancels the context of invocations
+// lasting longer than timeout, failing them with exception.Timeout. Zero timeout disables it
+func TimeoutMiddleware(timeout time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		if timeout <= 0 {
+			return next
+		}
+
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			response, err := next(ctx, request)
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, exception.NewErrorDescription(exception.Timeout,
+					fmt.Sprintf("request exceeded the %s timeout", timeout))
+			}
+			return response, err
+		}
+	}
+}
+
+// RetryMiddleware returns an endpoint middleware that retries failed invocations up to retries
+// times, waiting an exponential backoff with jitter between attempts. Only server-side errors
+// are retried, thus it must only wrap idempotent endpoints
+func RetryMiddleware(retries int, backoff, maxBackoff time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		if retries <= 0 {
+			return next
+		}
+
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			for attempt := 0; ; attempt++ {
+				response, err := next(ctx, request)
+				if err == nil || attempt >= retries || !isRetryable(err) {
+					return response, err
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil, err
+				case <-time.After(jitterBackoff(backoff, maxBackoff, attempt)):
+				}
+			}
+		}
+	}
+}
+
+// BulkheadMiddleware returns an endpoint middleware that limits concurrent invocations, invocations
+// exceeding maxConcurrent fail with exception.Unavailable. Every endpoint wrapped by the same
+// middleware shares the bulkhead. Zero maxConcurrent disables it
+func BulkheadMiddleware(maxConcurrent int) endpoint.Middleware {
+	if maxConcurrent <= 0 {
+		return func(next endpoint.Endpoint) endpoint.Endpoint {
+			return next
+		}
+	}
+
+	slots := make(chan struct{}, maxConcurrent)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				return nil, exception.NewErrorDescription(exception.Unavailable,
+					fmt.Sprintf("too many concurrent requests, maximum is %d", maxConcurrent))
+			}
+			defer func() {
+				<-slots
+			}()
+
+			return next(ctx, request)
+		}
+	}
+}
+
+// BreakerMiddleware returns an endpoint middleware that protects invocations using the given
+// circuit breaker, invocations rejected by the breaker fail with exception.Unavailable
+func BreakerMiddleware(cb *gobreaker.CircuitBreaker) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		next = circuitbreaker.Gobreaker(cb)(next)
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			response, err := next(ctx, request)
+			if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+				return nil, exception.NewErrorDescription(exception.Unavailable,
+					fmt.Sprintf("circuit breaker %s is %s", cb.Name(), cb.State()))
+			}
+			return response, err
+		}
+	}
+}
+
+// NewResiliencyBreaker returns a circuit breaker using the given policy, state changes are logged
+// and exposed through the alexandria_resiliency_breaker_state and
+// alexandria_resiliency_breaker_transitions_total Prometheus metrics
+func NewResiliencyBreaker(name string, policy config.ResiliencyPolicy, logger log.Logger) *gobreaker.CircuitBreaker {
+	breakerMetricsOnce.Do(func() {
+		breakerState = kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "alexandria",
+			Subsystem: "resiliency",
+			Name:      "breaker_state",
+			Help:      "Current circuit breaker state (0 closed, 1 half-open, 2 open).",
+		}, []string{"breaker"})
+		breakerTransitions = kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "alexandria",
+			Subsystem: "resiliency",
+			Name:      "breaker_transitions_total",
+			Help:      "Total of circuit breaker state changes.",
+		}, []string{"breaker", "from", "to"})
+	})
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	var readyToTrip func(counts gobreaker.Counts) bool
+	if policy.BreakerFailures > 0 {
+		readyToTrip = func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= policy.BreakerFailures
+		}
+	}
+
+	breakerState.With("breaker", name).Set(float64(gobreaker.StateClosed))
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        name,
+		MaxRequests: policy.BreakerMaxRequests,
+		Interval:    policy.BreakerInterval,
+		Timeout:     policy.BreakerTimeout,
+		ReadyToTrip: readyToTrip,
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			_ = logger.Log("msg", "circuit breaker state changed", "breaker", name, "from", from.String(),
+				"to", to.String())
+			breakerState.With("breaker", name).Set(float64(to))
+			breakerTransitions.With("breaker", name, "from", from.String(), "to", to.String()).Add(1)
+		},
+	})
+}
+
+// isRetryable reports whether the given error is a server-side error worth retrying
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, exception.Unavailable) {
+		return false
+	}
+
+	return httputil.ErrorToCode(err) >= 500
+}
+
+// jitterBackoff returns the attempt's exponential backoff, capped by maxBackoff, with equal jitter
+func jitterBackoff(backoff, maxBackoff time.Duration, attempt int) time.Duration {
+	d := backoff
+	for i := 0; i < attempt && (maxBackoff <= 0 || d < maxBackoff); i++ {
+		d *= 2
+	}
+	if maxBackoff > 0 && d > maxBackoff {
+		d = maxBackoff
+	}
+	if d <= 1 {
+		return d
+	}
+
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)))
+}
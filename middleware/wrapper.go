@@ -2,11 +2,12 @@ package middleware
 
 import (
 	"fmt"
-	"github.com/go-kit/kit/circuitbreaker"
+	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/ratelimit"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
-	"github.com/go-kit/kit/ratelimit"
+	kitratelimit "github.com/go-kit/kit/ratelimit"
 	kitoc "github.com/go-kit/kit/tracing/opencensus"
 	"github.com/go-kit/kit/tracing/opentracing"
 	"github.com/go-kit/kit/tracing/zipkin"
@@ -17,19 +18,63 @@ import (
 	"time"
 )
 
+// defaultResiliencyPolicy Circuit breaker settings used by endpoints without a policy
+var defaultResiliencyPolicy = config.ResiliencyPolicy{
+	BreakerMaxRequests: 100,
+	BreakerTimeout:     15 * time.Second,
+}
+
 // WrapResiliency inject fault-tolerant/resiliency patterns into the given endpoint
 func WrapResiliency(e endpoint.Endpoint, service, action string) endpoint.Endpoint {
-	limiter := rate.NewLimiter(rate.Every(time.Second), 1)
-	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
-		Name:          fmt.Sprintf("%s.%s", service, action),
-		MaxRequests:   100,
-		Interval:      0,
-		Timeout:       15 * time.Second,
-		ReadyToTrip:   nil,
-		OnStateChange: nil,
+	return WrapResiliencyLimiter(e, service, action, rate.NewLimiter(rate.Every(time.Second), 1))
+}
+
+// WrapResiliencyLimiter inject fault-tolerant/resiliency patterns into the given endpoint using the
+// given rate limiter, limiter might be updated at runtime using WatchResiliencyLimiter
+func WrapResiliencyLimiter(e endpoint.Endpoint, service, action string, limiter *rate.Limiter) endpoint.Endpoint {
+	e = kitratelimit.NewErroringLimiter(limiter)(e)
+	return BreakerMiddleware(newResiliencyBreaker(service, action, defaultResiliencyPolicy, nil))(e)
+}
+
+// WrapResiliencyKeyLimiter inject fault-tolerant/resiliency patterns into the given endpoint, requests
+// are rate limited per key (e.g. ratelimit.Keys.ContextByUser) using the given limiter, ratelimit.NewLimiter
+// enforces limits cluster-wide
+func WrapResiliencyKeyLimiter(e endpoint.Endpoint, service, action string, limiter ratelimit.Limiter,
+	key ratelimit.ContextKeyFunc) endpoint.Endpoint {
+	e = ratelimit.NewEndpointMiddleware(limiter, key)(e)
+	return BreakerMiddleware(newResiliencyBreaker(service, action, defaultResiliencyPolicy, nil))(e)
+}
+
+// WrapResiliencyPolicy inject the given policy's bulkhead, retries, circuit breaker and timeout into
+// the given endpoint, policies are obtained from config.Resiliency.PolicyFor. Retries are only made by
+// idempotent policies, each attempt gets its own timeout. Breaker state changes are logged using logger
+func WrapResiliencyPolicy(e endpoint.Endpoint, service, action string, policy config.ResiliencyPolicy,
+	logger log.Logger) endpoint.Endpoint {
+	e = TimeoutMiddleware(policy.Timeout)(e)
+	e = BreakerMiddleware(newResiliencyBreaker(service, action, policy, logger))(e)
+	if policy.Idempotent {
+		e = RetryMiddleware(policy.Retries, policy.RetryBackoff, policy.RetryMaxBackoff)(e)
+	}
+	return BulkheadMiddleware(policy.MaxConcurrent)(e)
+}
+
+func newResiliencyBreaker(service, action string, policy config.ResiliencyPolicy,
+	logger log.Logger) *gobreaker.CircuitBreaker {
+	return NewResiliencyBreaker(fmt.Sprintf("%s.%s", service, action), policy, logger)
+}
+
+// NewResiliencyLimiter Obtain a rate limiter from the kernel configuration
+func NewResiliencyLimiter(cfg *config.Kernel) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(cfg.Resiliency.RateLimit), cfg.Resiliency.RateBurst)
+}
+
+// WatchResiliencyLimiter updates the given limiter whenever alexandria.service.resiliency changes,
+// returns a function to stop watching
+func WatchResiliencyLimiter(w *config.Watcher, limiter *rate.Limiter) func() {
+	return w.Subscribe("alexandria.service.resiliency", func(c config.Change) {
+		limiter.SetLimit(rate.Limit(c.Current.Resiliency.RateLimit))
+		limiter.SetBurst(c.Current.Resiliency.RateBurst)
 	})
-	e = ratelimit.NewErroringLimiter(limiter)(e)
-	return circuitbreaker.Gobreaker(cb)(e)
 }
 
 type WrapInstrumentParams struct {
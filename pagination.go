@@ -1,6 +1,7 @@
 package core
 
 import (
+	"net/http"
 	"strconv"
 )
 
@@ -10,7 +11,8 @@ type PaginationParams struct {
 	Size  int
 }
 
-// NewPaginationParams Create a new pagination token and size, uses UUID v1 as Token/ID
+// NewPaginationParams Create a new pagination token and size, Token is an opaque cursor token (see
+// CursorEncoder)
 func NewPaginationParams(token, size string) *PaginationParams {
 	// Pagination default values
 	params := &PaginationParams{
@@ -31,3 +33,30 @@ func NewPaginationParams(token, size string) *PaginationParams {
 
 	return params
 }
+
+// NewPaginationParamsFromRequest Create pagination params from the page_token and page_size query
+// parameters
+func NewPaginationParamsFromRequest(r *http.Request) *PaginationParams {
+	q := r.URL.Query()
+	return NewPaginationParams(q.Get("page_token"), q.Get("page_size"))
+}
+
+// PageRequest gRPC request message holding page_token and page_size fields, implemented by the
+// generated protobuf getters
+type PageRequest interface {
+	GetPageToken() string
+	GetPageSize() int32
+}
+
+// NewPaginationParamsFromProto Create pagination params from a gRPC request message
+func NewPaginationParamsFromProto(req PageRequest) *PaginationParams {
+	return NewPaginationParams(req.GetPageToken(), strconv.Itoa(int(req.GetPageSize())))
+}
+
+// Page Paginated response, Items holds the page's slice of entities
+type Page struct {
+	Items         interface{} `json:"items"`
+	NextToken     string      `json:"next_token,omitempty"`
+	PrevToken     string      `json:"prev_token,omitempty"`
+	TotalEstimate int64       `json:"total_estimate,omitempty"`
+}
@@ -2,34 +2,319 @@ package persistence
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/exception"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"gocloud.dev/docstore"
-	_ "gocloud.dev/docstore/awsdynamodb"
+	"gocloud.dev/docstore/awsdynamodb"
+	"gocloud.dev/gcerrors"
 )
 
-// NewDynamoDBCollectionPool Obtain an AWS DynamoDB collection connection pool
-func NewDynamoDBCollectionPool(ctx context.Context, cfg *config.Kernel) (*docstore.Collection, func(), error) {
-	URL := fmt.Sprintf("dynamodb://%s?partition_key=%s", cfg.Docstore.Collection,
-		strings.ToLower(cfg.Docstore.PartitionKey))
+const (
+	// dynamoBatchGetSize Maximum items read by a DynamoDB BatchGetItem request
+	dynamoBatchGetSize = 100
+	// dynamoBatchWriteSize Maximum items written by a DynamoDB BatchWriteItem request
+	dynamoBatchWriteSize = 25
+)
 
-	if cfg.Docstore.AllowScan {
-		URL += "&allow_scans=true"
+// NewDynamoDBCollectionPool Obtain an AWS DynamoDB collection connection pool, the table and its
+// indexes are created first if alexandria.persistence.doc.dynamodb.provision is set
+func NewDynamoDBCollectionPool(ctx context.Context, cfg *config.Kernel) (*docstore.Collection, func(), error) {
+	db, err := NewDynamoDBClient(cfg.Docstore.DynamoDB)
+	if err != nil {
+		return nil, nil, err
 	}
-	if cfg.Docstore.SortKey != "" {
-		URL += "&sort_key=" + strings.ToLower(cfg.Docstore.SortKey)
+
+	if cfg.Docstore.DynamoDB.Provision {
+		if err = ProvisionDynamoDBTable(ctx, db, cfg.Docstore); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	db, err := docstore.OpenCollection(ctx, URL)
+	coll, err := awsdynamodb.OpenCollection(db, cfg.Docstore.Collection, strings.ToLower(cfg.Docstore.PartitionKey),
+		strings.ToLower(cfg.Docstore.SortKey), &awsdynamodb.Options{
+			AllowScans:    cfg.Docstore.AllowScan,
+			RevisionField: cfg.Docstore.RevisionField,
+		})
 	if err != nil {
 		return nil, nil, err
 	}
 
 	cleanup := func() {
-		_ = db.Close()
+		_ = coll.Close()
+	}
+
+	return coll, cleanup, nil
+}
+
+// NewDynamoDBClient Obtain an AWS DynamoDB client, credentials are taken from the AWS environment
+func NewDynamoDBClient(cfg config.DocstoreDynamoDB) (*dynamodb.DynamoDB, error) {
+	awsCfg := aws.Config{}
+	if cfg.Region != "" {
+		awsCfg.Region = aws.String(cfg.Region)
 	}
+	if cfg.Endpoint != "" {
+		awsCfg.Endpoint = aws.String(cfg.Endpoint)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            awsCfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dynamodb.New(sess), nil
+}
+
+// ProvisionDynamoDBTable creates the collection's table and its global secondary indexes if missing,
+// waiting until they are active. Meant for local development (e.g. DynamoDB Local), production tables
+// should be managed by infrastructure tooling
+func ProvisionDynamoDBTable(ctx context.Context, db dynamodbiface.DynamoDBAPI, cfg config.Docstore) error {
+	table := aws.String(cfg.Collection)
+	out, err := db.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: table})
+	var aerr awserr.Error
+	switch {
+	case errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException:
+		_, err = db.CreateTableWithContext(ctx, newDynamoDBTableInput(cfg))
+	case err == nil:
+		err = provisionDynamoDBIndexes(ctx, db, cfg, out.Table)
+	}
+	if err != nil {
+		return fmt.Errorf("provision dynamodb table %s: %w", cfg.Collection, err)
+	}
+
+	return db.WaitUntilTableExistsWithContext(ctx, &dynamodb.DescribeTableInput{TableName: table})
+}
+
+// provisionDynamoDBIndexes creates the configured indexes missing in the given table, DynamoDB allows
+// a single index creation per request
+func provisionDynamoDBIndexes(ctx context.Context, db dynamodbiface.DynamoDBAPI, cfg config.Docstore,
+	table *dynamodb.TableDescription) error {
+	existing := make(map[string]bool, len(table.GlobalSecondaryIndexes))
+	for _, index := range table.GlobalSecondaryIndexes {
+		existing[aws.StringValue(index.IndexName)] = true
+	}
+
+	for _, index := range cfg.DynamoDB.Indexes {
+		if existing[index.Name] {
+			continue
+		}
+
+		_, err := db.UpdateTableWithContext(ctx, &dynamodb.UpdateTableInput{
+			TableName: aws.String(cfg.Collection),
+			AttributeDefinitions: dynamoDBAttributes(cfg.DynamoDB.AttributeTypes, strings.ToLower(index.PartitionKey),
+				strings.ToLower(index.SortKey)),
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+				{Create: &dynamodb.CreateGlobalSecondaryIndexAction{
+					IndexName:             aws.String(index.Name),
+					KeySchema:             dynamoDBKeySchema(index.PartitionKey, index.SortKey),
+					Projection:            &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+					ProvisionedThroughput: dynamoDBThroughput(cfg.DynamoDB),
+				}},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if err = db.WaitUntilTableExistsWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(cfg.Collection),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newDynamoDBTableInput(cfg config.Docstore) *dynamodb.CreateTableInput {
+	keys := []string{strings.ToLower(cfg.PartitionKey), strings.ToLower(cfg.SortKey)}
+	indexes := make([]*dynamodb.GlobalSecondaryIndex, 0, len(cfg.DynamoDB.Indexes))
+	for _, index := range cfg.DynamoDB.Indexes {
+		keys = append(keys, strings.ToLower(index.PartitionKey), strings.ToLower(index.SortKey))
+		indexes = append(indexes, &dynamodb.GlobalSecondaryIndex{
+			IndexName:             aws.String(index.Name),
+			KeySchema:             dynamoDBKeySchema(index.PartitionKey, index.SortKey),
+			Projection:            &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+			ProvisionedThroughput: dynamoDBThroughput(cfg.DynamoDB),
+		})
+	}
+
+	input := &dynamodb.CreateTableInput{
+		TableName:             aws.String(cfg.Collection),
+		AttributeDefinitions:  dynamoDBAttributes(cfg.DynamoDB.AttributeTypes, keys...),
+		KeySchema:             dynamoDBKeySchema(cfg.PartitionKey, cfg.SortKey),
+		BillingMode:           aws.String(cfg.DynamoDB.BillingMode),
+		ProvisionedThroughput: dynamoDBThroughput(cfg.DynamoDB),
+	}
+	if len(indexes) > 0 {
+		input.GlobalSecondaryIndexes = indexes
+	}
+
+	return input
+}
+
+// dynamoDBAttributes returns the definitions of the given key attributes, empty and repeated keys
+// are skipped
+func dynamoDBAttributes(types map[string]string, keys ...string) []*dynamodb.AttributeDefinition {
+	definitions := make([]*dynamodb.AttributeDefinition, 0, len(keys))
+	defined := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key == "" || defined[key] {
+			continue
+		}
+		defined[key] = true
 
-	return db, cleanup, nil
+		kind := types[key]
+		if kind == "" {
+			kind = dynamodb.ScalarAttributeTypeS
+		}
+		definitions = append(definitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(key),
+			AttributeType: aws.String(kind),
+		})
+	}
+
+	return definitions
+}
+
+func dynamoDBKeySchema(partitionKey, sortKey string) []*dynamodb.KeySchemaElement {
+	schema := []*dynamodb.KeySchemaElement{
+		{AttributeName: aws.String(strings.ToLower(partitionKey)), KeyType: aws.String(dynamodb.KeyTypeHash)},
+	}
+	if sortKey != "" {
+		schema = append(schema, &dynamodb.KeySchemaElement{
+			AttributeName: aws.String(strings.ToLower(sortKey)),
+			KeyType:       aws.String(dynamodb.KeyTypeRange),
+		})
+	}
+
+	return schema
+}
+
+// dynamoDBThroughput returns the capacity of provisioned billing mode tables, nil otherwise
+func dynamoDBThroughput(cfg config.DocstoreDynamoDB) *dynamodb.ProvisionedThroughput {
+	if cfg.BillingMode != dynamodb.BillingModeProvisioned {
+		return nil
+	}
+
+	return &dynamodb.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(cfg.ReadCapacity),
+		WriteCapacityUnits: aws.Int64(cfg.WriteCapacity),
+	}
+}
+
+// PutIfAbsent creates the given document, fails with exception.EntityExists if a document with the
+// same key already exists
+func PutIfAbsent(ctx context.Context, c *docstore.Collection, doc docstore.Document) error {
+	return docstoreError(c.Create(ctx, doc))
+}
+
+// ReplaceIfMatch replaces the given document if its revision, obtained by a previous Get, is still
+// the current one, fails with exception.EntityExists otherwise. Documents without revision are
+// replaced if they exist, missing documents fail with exception.EntityNotFound
+func ReplaceIfMatch(ctx context.Context, c *docstore.Collection, doc docstore.Document) error {
+	return docstoreError(c.Replace(ctx, doc))
+}
+
+// UpdateIfMatch applies the given modifications if the document's revision, obtained by a previous
+// Get, is still the current one, fails with exception.EntityExists otherwise
+func UpdateIfMatch(ctx context.Context, c *docstore.Collection, doc docstore.Document, mods docstore.Mods) error {
+	return docstoreError(c.Update(ctx, doc, mods))
+}
+
+// UpdateOptimistic reads the given document, applies fn and replaces it unless another writer
+// modified it meanwhile, retrying the whole cycle up to retries times. Documents must hold the
+// collection's revision field (alexandria.persistence.doc.revision_field)
+//	* fn might be called more than once, it must only mutate doc
+func UpdateOptimistic(ctx context.Context, c *docstore.Collection, doc docstore.Document, retries int,
+	fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		if err := c.Get(ctx, doc); err != nil {
+			return docstoreError(err)
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+
+		err := c.Replace(ctx, doc)
+		if gcerrors.Code(err) != gcerrors.FailedPrecondition || attempt >= retries {
+			return docstoreError(err)
+		}
+	}
+}
+
+// BatchGet reads the given documents, using requests of up to 100 documents. Failures are returned
+// as a docstore.ActionListError indexed by docs position
+func BatchGet(ctx context.Context, c *docstore.Collection, docs []docstore.Document) error {
+	return runBatch(ctx, c, docs, dynamoBatchGetSize, func(l *docstore.ActionList, doc docstore.Document) {
+		l.Get(doc)
+	})
+}
+
+// BatchPut writes the given documents, using requests of up to 25 documents. Failures are returned
+// as a docstore.ActionListError indexed by docs position
+func BatchPut(ctx context.Context, c *docstore.Collection, docs []docstore.Document) error {
+	return runBatch(ctx, c, docs, dynamoBatchWriteSize, func(l *docstore.ActionList, doc docstore.Document) {
+		l.Put(doc)
+	})
+}
+
+func runBatch(ctx context.Context, c *docstore.Collection, docs []docstore.Document, size int,
+	add func(l *docstore.ActionList, doc docstore.Document)) error {
+	var errs docstore.ActionListError
+	for start := 0; start < len(docs); start += size {
+		end := start + size
+		if end > len(docs) {
+			end = len(docs)
+		}
+
+		l := c.Actions()
+		for _, doc := range docs[start:end] {
+			add(l, doc)
+		}
+		err := l.Do(ctx)
+		if err == nil {
+			continue
+		}
+
+		var chunkErrs docstore.ActionListError
+		if !errors.As(err, &chunkErrs) {
+			return err
+		}
+		for _, e := range chunkErrs {
+			e.Index += start
+			errs = append(errs, e)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// docstoreError maps docstore write conflicts to exception errors
+func docstoreError(err error) error {
+	switch gcerrors.Code(err) {
+	case gcerrors.OK:
+		return nil
+	case gcerrors.AlreadyExists:
+		return exception.NewErrorDescription(exception.EntityExists, "document already exists")
+	case gcerrors.FailedPrecondition:
+		return exception.NewErrorDescription(exception.EntityExists, "document was modified by another writer")
+	case gcerrors.NotFound:
+		return exception.NewErrorDescription(exception.EntityNotFound, "document not found")
+	default:
+		return err
+	}
 }
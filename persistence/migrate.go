@@ -0,0 +1,363 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrIrreversibleMigration A migration without down script was rolled back
+var ErrIrreversibleMigration = errors.New("migration has no down script")
+
+// defaultMigrationLockID Advisory lock key held while migrating, shared by every replica
+const defaultMigrationLockID int64 = 7241983441
+
+// Migration Versioned schema change, read from <version>_<name>.up.sql and <version>_<name>.down.sql
+// files
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus Migration and whether it was applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator Applies and rolls back schema migrations, keeping track of them in a migrations table.
+// A PostgreSQL advisory lock ensures a single replica migrates at a time
+type Migrator struct {
+	db     *sql.DB
+	source http.FileSystem
+	table  string
+	lockID int64
+	dryRun io.Writer
+}
+
+// MigratorOption Sets a migrator option
+type MigratorOption func(*Migrator)
+
+// WithMigrationsTable sets the table tracking applied migrations, schema_migrations by default
+func WithMigrationsTable(table string) MigratorOption {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithMigrationLockID sets the advisory lock key held while migrating
+func WithMigrationLockID(id int64) MigratorOption {
+	return func(m *Migrator) {
+		m.lockID = id
+	}
+}
+
+// WithDryRun writes the pending scripts to w instead of executing them, only the migrations table is
+// created if missing
+func WithDryRun(w io.Writer) MigratorOption {
+	return func(m *Migrator) {
+		m.dryRun = w
+	}
+}
+
+// NewMigrator creates a migrator reading scripts from source's root, use http.Dir to read them
+// from a directory or any generated http.FileSystem to embed them into the binary
+func NewMigrator(db *sql.DB, source http.FileSystem, opts ...MigratorOption) *Migrator {
+	m := &Migrator{
+		db:     db,
+		source: source,
+		table:  "schema_migrations",
+		lockID: defaultMigrationLockID,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// LoadMigrations reads the migrations stored in source's root, sorted by version
+func LoadMigrations(source http.FileSystem) ([]Migration, error) {
+	dir, err := source.Open("/")
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	files, err := dir.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".sql") {
+			continue
+		}
+
+		version, name, direction, err := parseMigrationName(f.Name())
+		if err != nil {
+			return nil, err
+		}
+		script, err := readMigration(source, f.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %d is duplicated (%s, %s)", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = script
+		} else {
+			m.Down = script
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// parseMigrationName splits <version>_<name>.<up|down>.sql file names
+func parseMigrationName(file string) (int64, string, string, error) {
+	base := strings.TrimSuffix(file, ".sql")
+	direction := path.Ext(base)
+	if direction != ".up" && direction != ".down" {
+		return 0, "", "", fmt.Errorf("migration file %s must end with .up.sql or .down.sql", file)
+	}
+	base = strings.TrimSuffix(base, direction)
+
+	parts := strings.SplitN(base, "_", 2)
+	version, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("migration file %s must start with a positive version", file)
+	}
+	name := ""
+	if len(parts) == 2 {
+		name = parts[1]
+	}
+
+	return version, name, strings.TrimPrefix(direction, "."), nil
+}
+
+func readMigration(source http.FileSystem, name string) (string, error) {
+	f, err := source.Open("/" + name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	script, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+
+	return string(script), nil
+}
+
+// Up applies every pending migration, returns the applied ones
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.UpTo(ctx, 0)
+}
+
+// UpTo applies pending migrations up to the given version, every pending migration if version is 0
+func (m *Migrator) UpTo(ctx context.Context, version int64) ([]Migration, error) {
+	applied := make([]Migration, 0)
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		status, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range status {
+			if s.Applied || (version > 0 && s.Version > version) {
+				continue
+			}
+			if err = m.apply(ctx, conn, s.Migration, s.Up,
+				fmt.Sprintf("INSERT INTO %s (version, name) VALUES ($1, $2)", pq.QuoteIdentifier(m.table)),
+				s.Version, s.Name); err != nil {
+				return err
+			}
+			applied = append(applied, s.Migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down rolls back the given number of applied migrations, newest first. Returns the rolled back ones
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	rolledBack := make([]Migration, 0, steps)
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		status, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(status) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			s := status[i]
+			if !s.Applied {
+				continue
+			}
+			if s.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrIrreversibleMigration, s.Version, s.Name)
+			}
+			if err = m.apply(ctx, conn, s.Migration, s.Down,
+				fmt.Sprintf("DELETE FROM %s WHERE version = $1", pq.QuoteIdentifier(m.table)),
+				s.Version); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, s.Migration)
+		}
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// Status returns every known migration and whether it was applied, sorted by version. Versions found
+// in the migrations table without scripts are reported as applied migrations with no name
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err = m.ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	return m.status(ctx, conn)
+}
+
+// locked runs fn holding the migrations advisory lock on a dedicated connection
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", m.lockID); err != nil {
+		return fmt.Errorf("migration lock: %w", err)
+	}
+	defer func() {
+		if _, errUnlock := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)",
+			m.lockID); errUnlock != nil && err == nil {
+			err = fmt.Errorf("migration unlock: %w", errUnlock)
+		}
+	}()
+
+	if err = m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, pq.QuoteIdentifier(m.table)))
+
+	return err
+}
+
+func (m *Migrator) status(ctx context.Context, conn *sql.Conn) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(m.source)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version, applied_at FROM %s",
+		pq.QuoteIdentifier(m.table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Version]
+		status = append(status, MigrationStatus{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+		delete(applied, migration.Version)
+	}
+	for version, appliedAt := range applied {
+		status = append(status, MigrationStatus{Migration: Migration{Version: version}, Applied: true,
+			AppliedAt: appliedAt})
+	}
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Version < status[j].Version
+	})
+
+	return status, nil
+}
+
+// apply runs a migration script and records it within a single transaction
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, script, record string,
+	args ...interface{}) error {
+	if m.dryRun != nil {
+		_, err := fmt.Fprintf(m.dryRun, "-- %d_%s\n%s\n", migration.Version, migration.Name,
+			strings.TrimSpace(script))
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, script); err == nil {
+		_, err = tx.ExecContext(ctx, record, args...)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	return tx.Commit()
+}
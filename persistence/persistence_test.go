@@ -0,0 +1,601 @@
+package persistence
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexandria-oss/core"
+	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/exception"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"gocloud.dev/docstore"
+	"gocloud.dev/docstore/memdocstore"
+)
+
+// flakyDriver fails the first dials, emulates a database starting up
+type flakyDriver struct {
+	failures int32
+	dials    int32
+}
+
+func (d *flakyDriver) Open(string) (driver.Conn, error) {
+	if atomic.AddInt32(&d.dials, 1) <= atomic.LoadInt32(&d.failures) {
+		return nil, errors.New("connection refused")
+	}
+	return new(fakeConn), nil
+}
+
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+var flaky = new(flakyDriver)
+
+// txDriver records executed statements, commits fail with the queued errors and queries return rows
+type txDriver struct {
+	mu         sync.Mutex
+	statements []string
+	commitErrs []error
+	rows       [][]driver.Value
+}
+
+func (d *txDriver) Open(string) (driver.Conn, error) {
+	return &txConn{d: d}, nil
+}
+
+func (d *txDriver) record(statement string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, statement)
+}
+
+func (d *txDriver) reset(commitErrs ...error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = nil
+	d.commitErrs = commitErrs
+	d.rows = nil
+}
+
+func (d *txDriver) log() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return strings.Join(d.statements, ";")
+}
+
+type txConn struct {
+	fakeConn
+	d *txDriver
+}
+
+func (c *txConn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN")
+	return c, nil
+}
+
+func (c *txConn) Exec(query string, _ []driver.Value) (driver.Result, error) {
+	c.d.record(query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *txConn) Query(query string, _ []driver.Value) (driver.Rows, error) {
+	c.d.record(query)
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	return &fakeRows{rows: c.d.rows}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"version", "applied_at"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func (c *txConn) Commit() error {
+	c.d.mu.Lock()
+	var err error
+	if len(c.d.commitErrs) > 0 {
+		err, c.d.commitErrs = c.d.commitErrs[0], c.d.commitErrs[1:]
+	}
+	c.d.mu.Unlock()
+
+	if err != nil {
+		c.d.record("ROLLBACK")
+		return err
+	}
+	c.d.record("COMMIT")
+	return nil
+}
+
+func (c *txConn) Rollback() error {
+	c.d.record("ROLLBACK")
+	return nil
+}
+
+var recorder = new(txDriver)
+
+func init() {
+	sql.Register("flaky", flaky)
+	sql.Register("recorder", recorder)
+}
+
+func TestPingWithRetry(t *testing.T) {
+	atomic.StoreInt32(&flaky.failures, 2)
+	db, err := sql.Open("flaky", "")
+	assert.Nil(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	assert.Nil(t, PingWithRetry(ctx, db, config.DBMSConnect{
+		Retries: 3,
+		Backoff: time.Millisecond,
+		Timeout: time.Second,
+	}))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&flaky.dials))
+
+	atomic.StoreInt32(&flaky.dials, 0)
+	atomic.StoreInt32(&flaky.failures, 5)
+	db.SetMaxIdleConns(0)
+	err = PingWithRetry(ctx, db, config.DBMSConnect{
+		Retries: 1,
+		Backoff: time.Millisecond,
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&flaky.dials))
+
+	reg := prometheus.NewPedanticRegistry()
+	assert.Nil(t, reg.Register(NewSQLStatsCollector(db, "alexandria")))
+	metrics, err := reg.Gather()
+	assert.Nil(t, err)
+	assert.Len(t, metrics, 8)
+}
+
+func TestUnitOfWork(t *testing.T) {
+	db, err := sql.Open("recorder", "")
+	assert.Nil(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	uow := NewUnitOfWork(db, WithTxRetries(2, time.Millisecond))
+	insert := func(ctx context.Context) error {
+		_, err := ExecutorFromContext(ctx, db).ExecContext(ctx, "INSERT")
+		return err
+	}
+
+	// Nested units run inside savepoints, failed ones are rolled back alone
+	recorder.reset()
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		_, ok := TxFromContext(ctx)
+		assert.True(t, ok)
+		if err := uow.Do(ctx, insert); err != nil {
+			return err
+		}
+		assert.NotNil(t, uow.Do(ctx, func(ctx context.Context) error {
+			return errors.New("invalid author")
+		}))
+		return insert(ctx)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN;SAVEPOINT sp_1;INSERT;RELEASE SAVEPOINT sp_1;SAVEPOINT sp_2;"+
+		"ROLLBACK TO SAVEPOINT sp_2;INSERT;COMMIT", recorder.log())
+
+	// Serialization failures are retried
+	recorder.reset(&pq.Error{Code: "40001"}, &pq.Error{Code: "40P01"})
+	assert.Nil(t, uow.Do(ctx, insert))
+	assert.Equal(t, "BEGIN;INSERT;ROLLBACK;BEGIN;INSERT;ROLLBACK;BEGIN;INSERT;COMMIT", recorder.log())
+
+	recorder.reset(&pq.Error{Code: "23505"})
+	assert.False(t, IsRetryableTx(uow.Do(ctx, insert)))
+	assert.Equal(t, "BEGIN;INSERT;ROLLBACK", recorder.log())
+
+	// Panics roll back the transaction
+	recorder.reset()
+	assert.Panics(t, func() {
+		_ = uow.Do(ctx, func(ctx context.Context) error {
+			_ = insert(ctx)
+			panic("unexpected state")
+		})
+	})
+	assert.Equal(t, "BEGIN;INSERT;ROLLBACK", recorder.log())
+}
+
+func TestMigrator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrations")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, script := range map[string]string{
+		"0001_create_authors.up.sql":   "CREATE TABLE authors (id TEXT PRIMARY KEY)",
+		"0001_create_authors.down.sql": "DROP TABLE authors",
+		"0002_add_name.up.sql":         "ALTER TABLE authors ADD COLUMN name TEXT",
+		"README.md":                    "not a migration",
+	} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(script), 0600))
+	}
+
+	migrations, err := LoadMigrations(http.Dir(dir))
+	assert.Nil(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, "create_authors", migrations[0].Name)
+	assert.Equal(t, "DROP TABLE authors", migrations[0].Down)
+
+	db, err := sql.Open("recorder", "")
+	assert.Nil(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	// Dry run only prints pending scripts
+	recorder.reset()
+	recorder.rows = [][]driver.Value{{int64(1), time.Now()}}
+	out := new(strings.Builder)
+	applied, err := NewMigrator(db, http.Dir(dir), WithDryRun(out)).Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, 1)
+	assert.Equal(t, "-- 2_add_name\nALTER TABLE authors ADD COLUMN name TEXT\n", out.String())
+	assert.NotContains(t, recorder.log(), "ALTER")
+
+	recorder.reset()
+	applied, err = NewMigrator(db, http.Dir(dir)).Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, 2)
+	log := recorder.log()
+	assert.True(t, strings.HasPrefix(log, "SELECT pg_advisory_lock($1)"))
+	assert.True(t, strings.HasSuffix(log, "SELECT pg_advisory_unlock($1)"))
+	assert.Contains(t, log, "BEGIN;CREATE TABLE authors (id TEXT PRIMARY KEY);"+
+		`INSERT INTO "schema_migrations" (version, name) VALUES ($1, $2);COMMIT`)
+
+	// Migrations without down script are irreversible
+	recorder.reset()
+	recorder.rows = [][]driver.Value{{int64(1), time.Now()}, {int64(2), time.Now()}}
+	_, err = NewMigrator(db, http.Dir(dir)).Down(ctx, 1)
+	assert.True(t, errors.Is(err, ErrIrreversibleMigration))
+
+	recorder.reset()
+	recorder.rows = [][]driver.Value{{int64(1), time.Now()}}
+	status, err := NewMigrator(db, http.Dir(dir)).Status(ctx)
+	assert.Nil(t, err)
+	assert.True(t, status[0].Applied)
+	assert.False(t, status[1].Applied)
+}
+
+func TestQueryBuilder(t *testing.T) {
+	schema := core.QuerySchema{
+		Sortable:   []string{"year", "name"},
+		Selectable: []string{"id", "name"},
+		Filters: map[string]core.FilterRule{
+			"name": {Operators: []core.Operator{core.OpEq, core.OpLike}},
+			"year": {Type: core.FilterInt, Operators: []core.Operator{core.OpGte, core.OpIn}},
+			"id":   {Type: core.FilterInt},
+		},
+	}
+	builder := NewQueryBuilder("authors", "id", schema, "id", "name", "year")
+
+	query, args, err := builder.SQL(&core.QueryParams{
+		Filter:     core.FilterParams{"name[like]": "Mario%", "year[in]": "1998,2003"},
+		Pagination: &core.PaginationParams{Token: "42", Size: 10},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT "id", "name", "year" FROM "authors" WHERE "name" LIKE $1 AND "year" IN ($2, $3) `+
+		`AND "id" > $4 ORDER BY "id" ASC LIMIT 10`, query)
+	assert.Equal(t, []interface{}{"Mario%", int64(1998), int64(2003), int64(42)}, args)
+
+	// Sort fields and sparse fieldsets are honored, cursors paginate both ways
+	encoder := core.NewCursorEncoder([]byte("secret"))
+	builder.Cursors(encoder)
+	params, err := schema.ParseRequest(httptest.NewRequest(http.MethodGet, "/v1/authors?sort=-year&fields=id,name",
+		nil))
+	assert.Nil(t, err)
+	assert.Equal(t, []core.SortField{{Field: "year", Descending: true}, {Field: "id"}}, builder.SortKeys(params))
+	params.Pagination.Token, err = encoder.Encode(&core.Cursor{Keys: []interface{}{2003, 42},
+		Direction: core.DirectionPrev})
+	assert.Nil(t, err)
+	query, args, err = builder.SQL(params)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT "id", "name" FROM "authors" WHERE (("year" > $1) OR ("year" = $2 AND "id" < $3)) `+
+		`ORDER BY "year" ASC, "id" DESC LIMIT 10`, query)
+	assert.Equal(t, []interface{}{int64(2003), int64(2003), int64(42)}, args)
+
+	// Cursors must hold every sort key
+	params.Pagination.Token, err = encoder.Encode(&core.Cursor{Keys: []interface{}{42}})
+	assert.Nil(t, err)
+	_, _, err = builder.SQL(params)
+	assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+	_, _, err = builder.SQL(&core.QueryParams{Pagination: &core.PaginationParams{Token: "42"}})
+	assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+	builder.Cursors(nil)
+
+	// Only allow-listed filters and operators are accepted
+	for _, filter := range []core.FilterParams{
+		{"password": "root"},
+		{"year[lt]": "2000"},
+		{"year": "2000"},
+		{"year[gte]": "two thousand"},
+	} {
+		_, _, err = builder.SQL(&core.QueryParams{Filter: filter})
+		assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+	}
+
+	coll, err := memdocstore.OpenCollection("id", nil)
+	assert.Nil(t, err)
+	defer coll.Close()
+	ctx := context.Background()
+	for i := 1; i <= 12; i++ {
+		assert.Nil(t, coll.Put(ctx, map[string]interface{}{"id": int64(i), "name": fmt.Sprint("author ", i),
+			"year": int64(1995 + i)}))
+	}
+
+	// Numeric keys are compared as numbers (10 follows 9)
+	q, fields, err := builder.Cursors(encoder).Docstore(coll, &core.QueryParams{
+		Fields:     []string{"id"},
+		Filter:     core.FilterParams{"year[gte]": "1997"},
+		Pagination: &core.PaginationParams{Token: cursorToken(t, encoder, 8), Size: 5},
+	})
+	assert.Nil(t, err)
+	iter := q.Get(ctx, fields...)
+	defer iter.Stop()
+	ids := make([]interface{}, 0)
+	for {
+		doc := map[string]interface{}{}
+		if err = iter.Next(ctx, doc); err != nil {
+			break
+		}
+		ids = append(ids, doc["id"])
+		assert.Nil(t, doc["name"])
+	}
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []interface{}{int64(9), int64(10), int64(11), int64(12)}, ids)
+
+	_, _, err = builder.Docstore(coll, &core.QueryParams{Filter: core.FilterParams{"name[like]": "Mario%"}})
+	assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+	_, _, err = builder.Docstore(coll, &core.QueryParams{Sort: []core.SortField{{Field: "year"}}})
+	assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+}
+
+func cursorToken(t *testing.T, encoder *core.CursorEncoder, keys ...interface{}) string {
+	token, err := encoder.Encode(&core.Cursor{Keys: keys, Direction: core.DirectionNext})
+	assert.Nil(t, err)
+	return token
+}
+
+// pongServer answers every Redis command with PONG
+func pongServer(t *testing.T) (int, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					// PING is sent as a single bulk string array
+					if strings.HasPrefix(line, "PING") || strings.HasPrefix(line, "ping") {
+						_, _ = conn.Write([]byte("+PONG\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().(*net.TCPAddr).Port, func() { _ = l.Close() }
+}
+
+func TestNewRedisPool(t *testing.T) {
+	port, stop := pongServer(t)
+	defer stop()
+
+	cfg := config.NewDefaultKernel()
+	cfg.InMemory.Host = "127.0.0.1"
+	cfg.InMemory.Port = port
+	cfg.InMemory.Connect = config.InMemoryConnect{Retries: 1, Backoff: time.Millisecond, Timeout: time.Second}
+	client, cleanup, err := NewRedisPool(cfg)
+	assert.Nil(t, err)
+	assert.NotNil(t, client)
+
+	reg := prometheus.NewPedanticRegistry()
+	assert.Nil(t, reg.Register(NewRedisStatsCollector(client.(RedisPoolStater), "test")))
+	metrics, err := reg.Gather()
+	assert.Nil(t, err)
+	assert.Len(t, metrics, 6)
+	cleanup()
+
+	// Unreachable nodes are reported instead of returning a nil client
+	stop()
+	client, _, err = NewRedisPool(cfg)
+	assert.NotNil(t, err)
+	assert.Nil(t, client)
+}
+
+type book struct {
+	ID               string `docstore:"id"`
+	Title            string `docstore:"title"`
+	Stock            int    `docstore:"stock"`
+	DocstoreRevision interface{}
+}
+
+func TestDocstoreWrites(t *testing.T) {
+	ctx := context.Background()
+	coll, err := memdocstore.OpenCollection("id", nil)
+	assert.Nil(t, err)
+	defer coll.Close()
+
+	assert.Nil(t, PutIfAbsent(ctx, coll, &book{ID: "1", Title: "Dune", Stock: 1}))
+	err = PutIfAbsent(ctx, coll, &book{ID: "1", Title: "Dune Messiah"})
+	assert.True(t, errors.Is(err, exception.EntityExists))
+
+	// Stale revisions are rejected
+	stale := &book{ID: "1"}
+	assert.Nil(t, coll.Get(ctx, stale))
+	current := &book{ID: "1"}
+	assert.Nil(t, coll.Get(ctx, current))
+	current.Stock = 2
+	assert.Nil(t, ReplaceIfMatch(ctx, coll, current))
+	stale.Stock = 10
+	assert.True(t, errors.Is(ReplaceIfMatch(ctx, coll, stale), exception.EntityExists))
+	assert.True(t, errors.Is(UpdateIfMatch(ctx, coll, stale, docstore.Mods{"stock": 10}), exception.EntityExists))
+	assert.True(t, errors.Is(ReplaceIfMatch(ctx, coll, &book{ID: "2"}), exception.EntityNotFound))
+
+	// Conflicting writers are retried
+	calls := 0
+	doc := &book{ID: "1"}
+	err = UpdateOptimistic(ctx, coll, doc, 1, func() error {
+		calls++
+		if calls == 1 {
+			other := &book{ID: "1"}
+			assert.Nil(t, coll.Get(ctx, other))
+			other.Stock += 5
+			assert.Nil(t, coll.Replace(ctx, other))
+		}
+		doc.Stock--
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Nil(t, coll.Get(ctx, current))
+	assert.Equal(t, 6, current.Stock)
+
+	// Batches span several requests, failures keep their docs position
+	docs := make([]docstore.Document, 0, 130)
+	for i := 0; i < 130; i++ {
+		docs = append(docs, &book{ID: "b" + strconv.Itoa(i), Title: "Book " + strconv.Itoa(i)})
+	}
+	assert.Nil(t, BatchPut(ctx, coll, docs))
+	gets := make([]docstore.Document, 0, len(docs)+1)
+	for i := range docs {
+		gets = append(gets, &book{ID: "b" + strconv.Itoa(i)})
+	}
+	gets = append(gets, &book{ID: "missing"})
+	err = BatchGet(ctx, coll, gets)
+	var errs docstore.ActionListError
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 130, errs[0].Index)
+	assert.Equal(t, "Book 129", gets[129].(*book).Title)
+}
+
+// dynamoDBStub records provisioning requests
+type dynamoDBStub struct {
+	dynamodbiface.DynamoDBAPI
+	table   *dynamodb.TableDescription
+	created *dynamodb.CreateTableInput
+	updated []*dynamodb.UpdateTableInput
+}
+
+func (s *dynamoDBStub) DescribeTableWithContext(aws.Context, *dynamodb.DescribeTableInput,
+	...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	if s.table == nil {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "table not found", nil)
+	}
+	return &dynamodb.DescribeTableOutput{Table: s.table}, nil
+}
+
+func (s *dynamoDBStub) CreateTableWithContext(_ aws.Context, in *dynamodb.CreateTableInput,
+	_ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	s.created = in
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (s *dynamoDBStub) UpdateTableWithContext(_ aws.Context, in *dynamodb.UpdateTableInput,
+	_ ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	s.updated = append(s.updated, in)
+	return &dynamodb.UpdateTableOutput{}, nil
+}
+
+func (s *dynamoDBStub) WaitUntilTableExistsWithContext(aws.Context, *dynamodb.DescribeTableInput,
+	...request.WaiterOption) error {
+	return nil
+}
+
+func TestProvisionDynamoDBTable(t *testing.T) {
+	cfg := config.NewDefaultKernel().Docstore
+	cfg.Collection = "books"
+	cfg.PartitionKey = "Book_ID"
+	cfg.SortKey = "published_at"
+	cfg.DynamoDB.BillingMode = dynamodb.BillingModeProvisioned
+	cfg.DynamoDB.AttributeTypes = map[string]string{"published_at": "N"}
+	cfg.DynamoDB.Indexes = []config.DocstoreIndex{
+		{Name: "by_author", PartitionKey: "author", SortKey: "published_at"},
+		{Name: "by_isbn", PartitionKey: "isbn"},
+	}
+
+	ctx := context.Background()
+	stub := new(dynamoDBStub)
+	assert.Nil(t, ProvisionDynamoDBTable(ctx, stub, cfg))
+	assert.NotNil(t, stub.created)
+	assert.Equal(t, "books", *stub.created.TableName)
+	assert.Equal(t, "book_id", *stub.created.KeySchema[0].AttributeName)
+	assert.Equal(t, dynamodb.KeyTypeRange, *stub.created.KeySchema[1].KeyType)
+	assert.Len(t, stub.created.GlobalSecondaryIndexes, 2)
+	assert.Equal(t, int64(5), *stub.created.GlobalSecondaryIndexes[0].ProvisionedThroughput.ReadCapacityUnits)
+	types := make(map[string]string)
+	for _, attr := range stub.created.AttributeDefinitions {
+		types[*attr.AttributeName] = *attr.AttributeType
+	}
+	assert.Equal(t, map[string]string{"book_id": "S", "published_at": "N", "author": "S", "isbn": "S"}, types)
+
+	// Existing tables only get their missing indexes
+	stub = &dynamoDBStub{table: &dynamodb.TableDescription{
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{{IndexName: aws.String("by_author")}},
+	}}
+	assert.Nil(t, ProvisionDynamoDBTable(ctx, stub, cfg))
+	assert.Nil(t, stub.created)
+	assert.Len(t, stub.updated, 1)
+	assert.Equal(t, "by_isbn", *stub.updated[0].GlobalSecondaryIndexUpdates[0].Create.IndexName)
+}
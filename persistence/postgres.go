@@ -3,25 +3,135 @@ package persistence
 import (
 	"context"
 	"database/sql"
+	"net/http"
 	"time"
 
 	"github.com/alexandria-oss/core/config"
+	"github.com/prometheus/client_golang/prometheus"
 	"gocloud.dev/postgres"
 )
 
-// NewPostgresPool Obtain a PostgreSQL connection pool
+// NewPostgresPool Obtain a PostgreSQL connection pool, the database is pinged with retries before
+// returning and pool statistics are exported as Prometheus metrics. Pending schema migrations are
+// applied if a migrations directory is set
 func NewPostgresPool(ctx context.Context, cfg *config.Kernel) (*sql.DB, func(), error) {
-	db, err := postgres.Open(ctx, cfg.DBMS.URL)
+	dsn, err := cfg.DBMS.DSN()
 	if err != nil {
 		return nil, nil, err
 	}
-	db.SetMaxOpenConns(50)
-	db.SetConnMaxLifetime(30 * time.Second)
-	db.SetMaxIdleConns(10)
+
+	db, err := postgres.Open(ctx, dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	db.SetMaxOpenConns(cfg.DBMS.Pool.MaxOpenConns)
+	db.SetConnMaxLifetime(cfg.DBMS.Pool.ConnMaxLifetime)
+	db.SetMaxIdleConns(cfg.DBMS.Pool.MaxIdleConns)
+
+	if err = PingWithRetry(ctx, db, cfg.DBMS.Connect); err != nil {
+		_ = db.Close()
+		return nil, nil, err
+	}
+
+	if cfg.DBMS.Migrations.Dir != "" {
+		_, err = NewMigrator(db, http.Dir(cfg.DBMS.Migrations.Dir),
+			WithMigrationsTable(cfg.DBMS.Migrations.Table)).Up(ctx)
+		if err != nil {
+			_ = db.Close()
+			return nil, nil, err
+		}
+	}
+
+	collector := NewSQLStatsCollector(db, cfg.DBMS.Database)
+	registered := prometheus.Register(collector) == nil
 
 	cleanup := func() {
+		if registered {
+			prometheus.Unregister(collector)
+		}
 		_ = db.Close()
 	}
 
 	return db, cleanup, nil
 }
+
+// PingWithRetry verifies the database connection, retrying with exponential backoff
+func PingWithRetry(ctx context.Context, db *sql.DB, cfg config.DBMSConnect) error {
+	backoff := cfg.Backoff
+	for attempt := 0; ; attempt++ {
+		pingCtx, cancel := ctx, context.CancelFunc(func() {})
+		if cfg.Timeout > 0 {
+			pingCtx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		}
+		err := db.PingContext(pingCtx)
+		cancel()
+		if err == nil || attempt >= cfg.Retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// sqlStatsCollector Exports database/sql pool statistics
+type sqlStatsCollector struct {
+	db *sql.DB
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+// NewSQLStatsCollector returns a Prometheus collector exporting the pool statistics of the given
+// database as alexandria_sql_* metrics
+func NewSQLStatsCollector(db *sql.DB, database string) prometheus.Collector {
+	labels := prometheus.Labels{"database": database}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("alexandria", "sql", name), help, nil, labels)
+	}
+
+	return &sqlStatsCollector{
+		db:                db,
+		maxOpen:           desc("max_open_connections", "Maximum number of open connections to the database."),
+		open:              desc("open_connections", "Number of established connections, both in use and idle."),
+		inUse:             desc("in_use_connections", "Number of connections currently in use."),
+		idle:              desc("idle_connections", "Number of idle connections."),
+		waitCount:         desc("wait_count_total", "Total number of connections waited for."),
+		waitDuration:      desc("wait_duration_seconds_total", "Total time blocked waiting for a new connection."),
+		maxIdleClosed:     desc("max_idle_closed_total", "Total number of connections closed due to SetMaxIdleConns."),
+		maxLifetimeClosed: desc("max_lifetime_closed_total", "Total number of connections closed due to SetConnMaxLifetime."),
+	}
+}
+
+func (c *sqlStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxLifetimeClosed
+}
+
+func (c *sqlStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
@@ -0,0 +1,297 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alexandria-oss/core"
+	"github.com/alexandria-oss/core/exception"
+	"github.com/lib/pq"
+	"gocloud.dev/docstore"
+)
+
+var sqlOperators = map[core.Operator]string{
+	core.OpEq:   "=",
+	core.OpNe:   "<>",
+	core.OpGt:   ">",
+	core.OpGte:  ">=",
+	core.OpLt:   "<",
+	core.OpLte:  "<=",
+	core.OpLike: "LIKE",
+}
+
+var docstoreOperators = map[core.Operator]string{
+	core.OpEq:  "=",
+	core.OpGt:  ">",
+	core.OpGte: ">=",
+	core.OpLt:  "<",
+	core.OpLte: "<=",
+}
+
+// QueryBuilder Translates core.QueryParams parsed by a core.QuerySchema into parameterized SQL and
+// docstore queries using keyset pagination. Filters, sort fields and sparse fieldsets are named
+// after their columns or document fields, the pagination token holds the last seen key unless
+// cursor tokens are enabled
+type QueryBuilder struct {
+	table   string
+	columns []string
+	key     string
+	schema  core.QuerySchema
+	cursors *core.CursorEncoder
+}
+
+// NewQueryBuilder creates a query builder selecting columns from table by default, filters are
+// validated and typed using the given schema. Pages are sorted by the requested sort fields followed
+// by key, which must be unique (e.g. the primary key)
+func NewQueryBuilder(table, key string, schema core.QuerySchema, columns ...string) *QueryBuilder {
+	return &QueryBuilder{
+		table:   table,
+		columns: columns,
+		key:     key,
+		schema:  schema,
+	}
+}
+
+// Cursors decodes pagination tokens as signed cursors holding every sort key (see SortKeys). Previous
+// pages are sorted in reverse order, their items must be reversed before responding
+func (b *QueryBuilder) Cursors(encoder *core.CursorEncoder) *QueryBuilder {
+	b.cursors = encoder
+	return b
+}
+
+// SortKeys returns the page's sort fields ending with key, cursors must hold the boundary item's
+// values of these fields in the same order
+func (b *QueryBuilder) SortKeys(params *core.QueryParams) []core.SortField {
+	keys := make([]core.SortField, 0)
+	if params != nil {
+		for _, s := range params.Sort {
+			keys = append(keys, s)
+			// Fields following a unique key never break ties
+			if s.Field == b.key {
+				return keys
+			}
+		}
+	}
+
+	return append(keys, core.SortField{Field: b.key})
+}
+
+// boundary returns the page's boundary values by sort key and whether the page precedes it, values
+// keep the type set in the schema's filter rules or the cursor's JSON type
+func (b *QueryBuilder) boundary(params *core.QueryParams, keys []core.SortField) ([]interface{}, bool,
+	error) {
+	if params == nil || params.Pagination == nil || params.Pagination.Token == "" {
+		return nil, false, nil
+	}
+	invalid := exception.NewErrorDescription(exception.InvalidFieldFormat,
+		fmt.Sprintf(exception.InvalidFieldFormatString, "page_token", "a valid page token"))
+
+	values, backward := []interface{}{params.Pagination.Token}, false
+	if b.cursors != nil {
+		cursor, err := b.cursors.Decode(params.Pagination.Token)
+		if err != nil {
+			return nil, false, err
+		}
+		values, backward = cursor.Keys, cursor.Backward()
+	}
+	if len(values) != len(keys) {
+		return nil, false, invalid
+	}
+
+	typed := make([]interface{}, 0, len(values))
+	for i, v := range values {
+		value, err := b.keyValue(keys[i].Field, v)
+		if err != nil {
+			return nil, false, invalid
+		}
+		typed = append(typed, value)
+	}
+
+	return typed, backward, nil
+}
+
+// keyValue converts a boundary value to the field's filter rule type, numbers without rules are kept
+// as integers if possible
+func (b *QueryBuilder) keyValue(field string, value interface{}) (interface{}, error) {
+	rule, hasRule := b.schema.Filters[field]
+	switch v := value.(type) {
+	case json.Number:
+		if hasRule {
+			return rule.Parse(field, v.String())
+		}
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case string:
+		if hasRule {
+			return rule.Parse(field, v)
+		}
+	}
+
+	return value, nil
+}
+
+// selected returns the requested sparse fieldset or the default columns
+func (b *QueryBuilder) selected(params *core.QueryParams) []string {
+	if params != nil && len(params.Fields) > 0 {
+		return params.Fields
+	}
+
+	return b.columns
+}
+
+// Conditions parses and validates the given filters, sorted by filter key
+func (b *QueryBuilder) Conditions(params *core.QueryParams) ([]core.Condition, error) {
+	if params == nil {
+		return nil, nil
+	}
+
+	return b.schema.Conditions(params.Filter)
+}
+
+// SQL returns a parameterized PostgreSQL query selecting the requested page
+func (b *QueryBuilder) SQL(params *core.QueryParams) (string, []interface{}, error) {
+	conditions, err := b.Conditions(params)
+	if err != nil {
+		return "", nil, err
+	}
+	keys := b.SortKeys(params)
+	boundary, backward, err := b.boundary(params, keys)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := make([]interface{}, 0, len(conditions)+len(boundary))
+	placeholder := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := make([]string, 0, len(conditions)+1)
+	for _, c := range conditions {
+		column := pq.QuoteIdentifier(c.Field)
+		if c.Operator == core.OpIn {
+			placeholders := make([]string, 0, len(c.Values))
+			for _, v := range c.Values {
+				placeholders = append(placeholders, placeholder(v))
+			}
+			where = append(where, fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")))
+			continue
+		}
+		where = append(where, fmt.Sprintf("%s %s %s", column, sqlOperators[c.Operator], placeholder(c.Values[0])))
+	}
+
+	// Keyset: (k1 > v1) OR (k1 = v1 AND k2 > v2) ..., operators follow each key's direction
+	if len(boundary) > 0 {
+		alternatives := make([]string, 0, len(keys))
+		for i, k := range keys {
+			terms := make([]string, 0, i+1)
+			for j := 0; j < i; j++ {
+				terms = append(terms, fmt.Sprintf("%s = %s", pq.QuoteIdentifier(keys[j].Field),
+					placeholder(boundary[j])))
+			}
+			op := ">"
+			if k.Descending != backward {
+				op = "<"
+			}
+			terms = append(terms, fmt.Sprintf("%s %s %s", pq.QuoteIdentifier(k.Field), op, placeholder(boundary[i])))
+			alternatives = append(alternatives, strings.Join(terms, " AND "))
+		}
+		if len(alternatives) == 1 {
+			where = append(where, alternatives[0])
+		} else {
+			where = append(where, "(("+strings.Join(alternatives, ") OR (")+"))")
+		}
+	}
+
+	columns := make([]string, 0, len(b.columns))
+	for _, c := range b.selected(params) {
+		columns = append(columns, pq.QuoteIdentifier(c))
+	}
+	if len(columns) == 0 {
+		columns = append(columns, "*")
+	}
+	order := make([]string, 0, len(keys))
+	for _, k := range keys {
+		direction := "ASC"
+		if k.Descending != backward {
+			direction = "DESC"
+		}
+		order = append(order, pq.QuoteIdentifier(k.Field)+" "+direction)
+	}
+
+	query := new(strings.Builder)
+	fmt.Fprintf(query, "SELECT %s FROM %s", strings.Join(columns, ", "), pq.QuoteIdentifier(b.table))
+	if len(where) > 0 {
+		fmt.Fprintf(query, " WHERE %s", strings.Join(where, " AND "))
+	}
+	fmt.Fprintf(query, " ORDER BY %s", strings.Join(order, ", "))
+	if params != nil && params.Pagination != nil && params.Pagination.Size > 0 {
+		fmt.Fprintf(query, " LIMIT %d", params.Pagination.Size)
+	}
+
+	return query.String(), args, nil
+}
+
+// Query runs the requested page's query, inside the context's unit of work if any
+func (b *QueryBuilder) Query(ctx context.Context, db *sql.DB, params *core.QueryParams) (*sql.Rows, error) {
+	query, args, err := b.SQL(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return ExecutorFromContext(ctx, db).QueryContext(ctx, query, args...)
+}
+
+// Docstore returns a query selecting the requested page from the collection and the field paths of
+// the sparse fieldset to be passed to Query.Get. Docstore supports neither ne, like nor in operators
+// nor sorting by fields other than key, such requests are rejected
+func (b *QueryBuilder) Docstore(coll *docstore.Collection, params *core.QueryParams) (*docstore.Query,
+	[]docstore.FieldPath, error) {
+	conditions, err := b.Conditions(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := b.SortKeys(params)
+	if len(keys) > 1 {
+		return nil, nil, exception.NewErrorDescription(exception.InvalidFieldFormat,
+			fmt.Sprintf("document collections only sort by %s", b.key))
+	}
+	boundary, backward, err := b.boundary(params, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	q := coll.Query()
+	for _, c := range conditions {
+		op, ok := docstoreOperators[c.Operator]
+		if !ok {
+			return nil, nil, exception.NewErrorDescription(exception.InvalidFieldFormat,
+				fmt.Sprintf("operator %s is not supported by document collections", c.Operator))
+		}
+		q = q.Where(docstore.FieldPath(c.Field), op, c.Values[0])
+	}
+
+	op, order := ">", docstore.Ascending
+	if keys[0].Descending != backward {
+		op, order = "<", docstore.Descending
+	}
+	if len(boundary) > 0 {
+		q = q.Where(docstore.FieldPath(b.key), op, boundary[0])
+	}
+	q = q.OrderBy(b.key, order)
+	if params != nil && params.Pagination != nil && params.Pagination.Size > 0 {
+		q = q.Limit(params.Pagination.Size)
+	}
+
+	fields := make([]docstore.FieldPath, 0)
+	for _, f := range b.selected(params) {
+		fields = append(fields, docstore.FieldPath(f))
+	}
+
+	return q, fields, nil
+}
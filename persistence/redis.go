@@ -1,54 +1,224 @@
 package persistence
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"strconv"
 	"time"
 
 	"github.com/alexandria-oss/core/config"
 	"github.com/go-redis/redis/v7"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-// NewRedisPool Obtain a Redis connection pool
-func NewRedisPool(cfg *config.Kernel) (*redis.Client, func(), error) {
-	db, err := strconv.Atoi(cfg.InMemory.Database)
+// NewRedisPool Obtain a Redis connection pool, a Sentinel failover client if a master name is set or
+// a Cluster client if cluster addresses are set. Redis is pinged with retries before returning and pool
+// statistics are exported as Prometheus metrics
+func NewRedisPool(cfg *config.Kernel) (redis.UniversalClient, func(), error) {
+	client, err := newRedisClient(cfg)
 	if err != nil {
-		db = 0
+		return nil, nil, err
 	}
 
-	client := redis.NewClient(&redis.Options{
-		Network:            cfg.InMemory.Network,
-		Addr:               cfg.InMemory.Host + fmt.Sprintf(":%d", cfg.InMemory.Port),
-		Dialer:             nil,
-		OnConnect:          nil,
-		Password:           cfg.InMemory.Password,
-		DB:                 db,
-		MaxRetries:         10,
-		MinRetryBackoff:    0,
-		MaxRetryBackoff:    0,
-		DialTimeout:        30 * time.Second,
-		ReadTimeout:        15 * time.Second,
-		WriteTimeout:       15 * time.Second,
-		PoolSize:           100,
-		MinIdleConns:       32,
-		MaxConnAge:         0,
-		PoolTimeout:        24 * time.Second,
-		IdleTimeout:        30 * time.Second,
-		IdleCheckFrequency: 0,
-		TLSConfig:          nil,
-		Limiter:            nil,
-	})
+	if err = pingRedisWithRetry(context.Background(), client, cfg.InMemory.Connect); err != nil {
+		_ = client.Close()
+		return nil, nil, fmt.Errorf("redis ping: %w", err)
+	}
+
+	var collector prometheus.Collector
+	registered := false
+	if stats, ok := client.(RedisPoolStater); ok {
+		collector = NewRedisStatsCollector(stats, redisName(cfg))
+		registered = prometheus.Register(collector) == nil
+	}
 
 	cleanup := func() {
-		if client != nil {
-			_ = client.Close()
+		if registered {
+			prometheus.Unregister(collector)
 		}
+		_ = client.Close()
 	}
 
-	err = client.Ping().Err()
+	return client, cleanup, nil
+}
+
+func newRedisClient(cfg *config.Kernel) (redis.UniversalClient, error) {
+	var tlsCfg *tls.Config
+	if cfg.InMemory.TLS.Enabled {
+		var err error
+		if tlsCfg, err = newRedisTLSConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := strconv.Atoi(cfg.InMemory.Database)
 	if err != nil {
-		return nil, cleanup, nil
+		db = 0
 	}
 
-	return client, cleanup, nil
+	switch {
+	case len(cfg.InMemory.Cluster.Addresses) > 0:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.InMemory.Cluster.Addresses,
+			Password:     cfg.InMemory.Password,
+			MaxRetries:   10,
+			DialTimeout:  cfg.InMemory.Connect.Timeout,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			PoolSize:     100,
+			MinIdleConns: 32,
+			PoolTimeout:  24 * time.Second,
+			IdleTimeout:  30 * time.Second,
+			TLSConfig:    tlsCfg,
+		}), nil
+	case cfg.InMemory.Sentinel.MasterName != "":
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.InMemory.Sentinel.MasterName,
+			SentinelAddrs:    cfg.InMemory.Sentinel.Addresses,
+			SentinelPassword: cfg.InMemory.Sentinel.Password,
+			Password:         cfg.InMemory.Password,
+			DB:               db,
+			MaxRetries:       10,
+			DialTimeout:      cfg.InMemory.Connect.Timeout,
+			ReadTimeout:      15 * time.Second,
+			WriteTimeout:     15 * time.Second,
+			PoolSize:         100,
+			MinIdleConns:     32,
+			PoolTimeout:      24 * time.Second,
+			IdleTimeout:      30 * time.Second,
+			TLSConfig:        tlsCfg,
+		}), nil
+	default:
+		return redis.NewClient(&redis.Options{
+			Network:      cfg.InMemory.Network,
+			Addr:         net.JoinHostPort(cfg.InMemory.Host, strconv.Itoa(cfg.InMemory.Port)),
+			Password:     cfg.InMemory.Password,
+			DB:           db,
+			MaxRetries:   10,
+			DialTimeout:  cfg.InMemory.Connect.Timeout,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			PoolSize:     100,
+			MinIdleConns: 32,
+			PoolTimeout:  24 * time.Second,
+			IdleTimeout:  30 * time.Second,
+			TLSConfig:    tlsCfg,
+		}), nil
+	}
+}
+
+func newRedisTLSConfig(cfg *config.Kernel) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		InsecureSkipVerify: cfg.InMemory.TLS.InsecureSkipVerify,
+	}
+
+	if cfg.InMemory.TLS.CAFile != "" {
+		ca, err := ioutil.ReadFile(cfg.InMemory.TLS.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.InMemory.TLS.CAFile)
+		}
+	}
+
+	if cfg.InMemory.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.InMemory.TLS.CertFile, cfg.InMemory.TLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
+
+// pingRedisWithRetry verifies the Redis connection, retrying with exponential backoff
+func pingRedisWithRetry(ctx context.Context, client redis.UniversalClient, cfg config.InMemoryConnect) error {
+	backoff := cfg.Backoff
+	for attempt := 0; ; attempt++ {
+		err := client.Ping().Err()
+		if err == nil || attempt >= cfg.Retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// redisName returns the metrics label of the configured deployment
+func redisName(cfg *config.Kernel) string {
+	switch {
+	case len(cfg.InMemory.Cluster.Addresses) > 0:
+		return "cluster"
+	case cfg.InMemory.Sentinel.MasterName != "":
+		return cfg.InMemory.Sentinel.MasterName
+	default:
+		return net.JoinHostPort(cfg.InMemory.Host, strconv.Itoa(cfg.InMemory.Port))
+	}
+}
+
+// RedisPoolStater Reports pool statistics, implemented by single node, failover and cluster clients
+type RedisPoolStater interface {
+	PoolStats() *redis.PoolStats
+}
+
+// redisStatsCollector Exports Redis pool statistics
+type redisStatsCollector struct {
+	client RedisPoolStater
+
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	total      *prometheus.Desc
+	idle       *prometheus.Desc
+	staleConns *prometheus.Desc
+}
+
+// NewRedisStatsCollector returns a Prometheus collector exporting the pool statistics of the given
+// client as alexandria_redis_* metrics
+func NewRedisStatsCollector(client RedisPoolStater, name string) prometheus.Collector {
+	labels := prometheus.Labels{"redis": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("alexandria", "redis", metric), help, nil, labels)
+	}
+
+	return &redisStatsCollector{
+		client:     client,
+		hits:       desc("pool_hits_total", "Total number of times a free connection was found in the pool."),
+		misses:     desc("pool_misses_total", "Total number of times a free connection was not found in the pool."),
+		timeouts:   desc("pool_timeouts_total", "Total number of times a wait timeout occurred."),
+		total:      desc("connections", "Number of connections in the pool."),
+		idle:       desc("idle_connections", "Number of idle connections in the pool."),
+		staleConns: desc("stale_connections_total", "Total number of stale connections removed from the pool."),
+	}
+}
+
+func (c *redisStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.total
+	ch <- c.idle
+	ch <- c.staleConns
+}
+
+func (c *redisStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(stats.StaleConns))
 }
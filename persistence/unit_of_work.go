@@ -0,0 +1,174 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Executor Runs SQL statements, implemented by both *sql.DB and *sql.Tx
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// txContextKey Context key of the unit of work's transaction
+type txContextKey struct{}
+
+// txState Transaction shared by a unit of work and its nested units
+type txState struct {
+	tx         *sql.Tx
+	savepoints int32
+}
+
+// TxFromContext returns the transaction started by a unit of work, if any
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	state, ok := ctx.Value(txContextKey{}).(*txState)
+	if !ok {
+		return nil, false
+	}
+
+	return state.tx, true
+}
+
+// ExecutorFromContext returns the context's transaction or db if the context holds none, repositories
+// use it to take part in the caller's unit of work transparently
+func ExecutorFromContext(ctx context.Context, db *sql.DB) Executor {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// UnitOfWork Runs functions atomically inside a database transaction stored in the context
+type UnitOfWork struct {
+	db      *sql.DB
+	opts    *sql.TxOptions
+	retries int
+	backoff time.Duration
+}
+
+// UnitOfWorkOption Sets a unit of work option
+type UnitOfWorkOption func(*UnitOfWork)
+
+// WithTxOptions sets the isolation level and read-only mode of the transactions
+func WithTxOptions(opts *sql.TxOptions) UnitOfWorkOption {
+	return func(u *UnitOfWork) {
+		u.opts = opts
+	}
+}
+
+// WithTxRetries sets the attempts made after a serialization failure or deadlock, backoff is doubled
+// on every attempt
+func WithTxRetries(retries int, backoff time.Duration) UnitOfWorkOption {
+	return func(u *UnitOfWork) {
+		u.retries = retries
+		u.backoff = backoff
+	}
+}
+
+// NewUnitOfWork creates a unit of work, transactions are retried three times by default
+func NewUnitOfWork(db *sql.DB, opts ...UnitOfWorkOption) *UnitOfWork {
+	u := &UnitOfWork{
+		db:      db,
+		retries: 3,
+		backoff: 50 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+
+	return u
+}
+
+// Do runs fn inside a transaction, committed if fn succeeds and rolled back if fn fails or panics.
+// Repositories must obtain their Executor from fn's context. Units nested in fn run inside a savepoint
+// of the same transaction, serialization failures and deadlocks retry the whole outermost unit
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if state, ok := ctx.Value(txContextKey{}).(*txState); ok {
+		return u.savepoint(ctx, state, fn)
+	}
+
+	backoff := u.backoff
+	for attempt := 0; ; attempt++ {
+		err := u.run(ctx, fn)
+		if err == nil || attempt >= u.retries || !IsRetryableTx(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// run executes fn inside a new transaction
+func (u *UnitOfWork) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx, err := u.db.BeginTx(ctx, u.opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txContextKey{}, &txState{tx: tx})); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, errRollback)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// savepoint executes fn inside a savepoint of the context's transaction
+func (u *UnitOfWork) savepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) (err error) {
+	name := fmt.Sprintf("sp_%d", atomic.AddInt32(&state.savepoints, 1))
+	if _, err = state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+
+	if err = fn(ctx); err != nil {
+		if _, errRollback := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); errRollback != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, errRollback)
+		}
+		return err
+	}
+
+	_, err = state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// IsRetryableTx reports whether the error is a PostgreSQL serialization failure (40001) or
+// deadlock (40P01), transactions failing with them might succeed if retried
+func IsRetryableTx(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
@@ -1,4 +1,321 @@
 package core
 
-// FilterParams Optional fields for query filtering
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexandria-oss/core/exception"
+)
+
+// FilterParams Optional fields for query filtering, keys might set an operator as name[operator]
+// (e.g. year[gte])
 type FilterParams map[string]string
+
+// FilterType Filter value type
+type FilterType int
+
+const (
+	FilterString FilterType = iota
+	FilterInt
+	FilterFloat
+	FilterBool
+	// FilterTime RFC3339 timestamps or RFC3339Micro dates
+	FilterTime
+)
+
+// Operator Filter comparison operator, set as name[operator] in FilterParams keys (e.g. year[gte]),
+// name alone compares by equality
+type Operator string
+
+const (
+	OpEq   Operator = "eq"
+	OpNe   Operator = "ne"
+	OpGt   Operator = "gt"
+	OpGte  Operator = "gte"
+	OpLt   Operator = "lt"
+	OpLte  Operator = "lte"
+	OpLike Operator = "like"
+	// OpIn Matches any comma-separated value
+	OpIn Operator = "in"
+)
+
+// FilterRule Allowed filter, values are validated against its type and range
+type FilterRule struct {
+	Type FilterType
+	// Operators Allowed operators, eq only if empty
+	Operators []Operator
+	// Min Inclusive lower bound of numeric filters, ignored if Min equals Max
+	Min float64
+	// Max Exclusive upper bound of numeric filters
+	Max float64
+}
+
+// QuerySchema Sort fields, selectable fields and filters accepted by a list operation
+type QuerySchema struct {
+	Sortable   []string
+	Selectable []string
+	Filters    map[string]FilterRule
+}
+
+// Condition Parsed filter condition, values are converted to the rule's type (string, int64, float64,
+// bool or time.Time)
+type Condition struct {
+	Field    string
+	Operator Operator
+	Values   []interface{}
+}
+
+// SortField Sort field and direction
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// QueryParams Parsed list operation parameters
+type QueryParams struct {
+	Sort []SortField
+	// Fields Sparse fieldset, every field if empty
+	Fields     []string
+	Filter     FilterParams
+	Pagination *PaginationParams
+}
+
+// reservedParams Query parameters never parsed as filters
+var reservedParams = map[string]bool{
+	"sort":       true,
+	"fields":     true,
+	"page_token": true,
+	"page_size":  true,
+}
+
+// ParseRequest parses sort (?sort=-created_at,name), sparse fieldset (?fields=id,title), pagination
+// and filter (?year[gte]=2000) query parameters, returns exception errors if any parameter is not
+// allowed by the schema or has an invalid value
+func (s QuerySchema) ParseRequest(r *http.Request) (*QueryParams, error) {
+	q := r.URL.Query()
+	filter := make(FilterParams)
+	for k, v := range q {
+		if !reservedParams[k] && len(v) > 0 {
+			filter[k] = v[0]
+		}
+	}
+
+	return s.parse(q.Get("sort"), splitList(q.Get("fields")), filter, NewPaginationParamsFromRequest(r))
+}
+
+// ListRequest gRPC request message holding sort, fields and filter fields, implemented by the
+// generated protobuf getters of:
+//
+//	string sort = 1;
+//	repeated string fields = 2;
+//	map<string, string> filter = 3;
+//	string page_token = 4;
+//	int32 page_size = 5;
+type ListRequest interface {
+	PageRequest
+	GetSort() string
+	GetFields() []string
+	GetFilter() map[string]string
+}
+
+// ParseProto parses a gRPC list request message, applying the same rules as ParseRequest
+func (s QuerySchema) ParseProto(req ListRequest) (*QueryParams, error) {
+	return s.parse(req.GetSort(), req.GetFields(), req.GetFilter(), NewPaginationParamsFromProto(req))
+}
+
+func (s QuerySchema) parse(sortParam string, fields []string, filter FilterParams,
+	page *PaginationParams) (*QueryParams, error) {
+	params := &QueryParams{
+		Sort:       make([]SortField, 0),
+		Fields:     make([]string, 0, len(fields)),
+		Filter:     make(FilterParams, len(filter)),
+		Pagination: page,
+	}
+
+	for _, f := range splitList(sortParam) {
+		field := SortField{Field: strings.TrimPrefix(f, "-"), Descending: strings.HasPrefix(f, "-")}
+		if !contains(s.Sortable, field.Field) {
+			return nil, exception.NewErrorDescription(exception.InvalidFieldFormat,
+				fmt.Sprintf(exception.InvalidFieldFormatString, "sort", "one of "+strings.Join(s.Sortable, ", ")))
+		}
+		params.Sort = append(params.Sort, field)
+	}
+
+	for _, f := range fields {
+		if !contains(s.Selectable, f) {
+			return nil, exception.NewErrorDescription(exception.InvalidFieldFormat,
+				fmt.Sprintf(exception.InvalidFieldFormatString, "fields", "one of "+strings.Join(s.Selectable, ", ")))
+		}
+		params.Fields = append(params.Fields, f)
+	}
+
+	conditions, err := s.Conditions(filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conditions {
+		if c.Operator == OpEq {
+			params.Filter[c.Field] = filter.raw(c.Field, c.Operator)
+			continue
+		}
+		params.Filter[c.Field+"["+string(c.Operator)+"]"] = filter.raw(c.Field, c.Operator)
+	}
+
+	return params, nil
+}
+
+// Conditions parses the given filters using the schema's rules, sorted by filter key. Returns exception
+// errors if any filter is not allowed or has an invalid value
+func (s QuerySchema) Conditions(filter FilterParams) ([]Condition, error) {
+	keys := make([]string, 0, len(filter))
+	for k := range filter {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	conditions := make([]Condition, 0, len(filter))
+	for _, k := range keys {
+		name, op := parseFilterKey(k)
+		rule, ok := s.Filters[name]
+		if !ok {
+			return nil, exception.NewErrorDescription(exception.InvalidFieldFormat,
+				fmt.Sprintf("request field %s is not filterable", name))
+		}
+		operators := rule.Operators
+		if len(operators) == 0 {
+			operators = []Operator{OpEq}
+		}
+		if !containsOperator(operators, op) {
+			names := make([]string, 0, len(operators))
+			for _, o := range operators {
+				names = append(names, string(o))
+			}
+			return nil, exception.NewErrorDescription(exception.InvalidFieldFormat,
+				fmt.Sprintf(exception.InvalidFieldFormatString, k, "one of "+strings.Join(names, ", ")))
+		}
+
+		raw := []string{filter[k]}
+		if op == OpIn {
+			raw = strings.Split(filter[k], ",")
+		}
+		values := make([]interface{}, 0, len(raw))
+		for _, r := range raw {
+			value, err := rule.Parse(k, r)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		conditions = append(conditions, Condition{Field: name, Operator: op, Values: values})
+	}
+
+	return conditions, nil
+}
+
+// raw returns the filter's raw value, eq filters might be set with or without operator
+func (f FilterParams) raw(name string, op Operator) string {
+	if v, ok := f[name+"["+string(op)+"]"]; ok {
+		return v
+	}
+
+	return f[name]
+}
+
+// parseFilterKey splits a name[operator] filter key
+func parseFilterKey(key string) (string, Operator) {
+	if i := strings.IndexByte(key, '['); i > 0 && strings.HasSuffix(key, "]") {
+		return key[:i], Operator(key[i+1 : len(key)-1])
+	}
+
+	return key, OpEq
+}
+
+// Parse converts the filter's value into the rule's type (string, int64, float64, bool or time.Time),
+// checking its range
+func (r FilterRule) Parse(key, value string) (interface{}, error) {
+	var parsed interface{}
+	var number float64
+	var err error
+	switch r.Type {
+	case FilterInt:
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 64)
+		parsed, number = i, float64(i)
+	case FilterFloat:
+		number, err = strconv.ParseFloat(value, 64)
+		parsed = number
+	case FilterBool:
+		parsed, err = strconv.ParseBool(value)
+	case FilterTime:
+		var t time.Time
+		if t, err = time.Parse(time.RFC3339, value); err != nil {
+			t, err = time.Parse(RFC3339Micro, value)
+		}
+		parsed = t
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return nil, exception.NewErrorDescription(exception.InvalidFieldFormat,
+			fmt.Sprintf(exception.InvalidFieldFormatString, key, r.Type.String()))
+	}
+
+	if (r.Type == FilterInt || r.Type == FilterFloat) && r.Min != r.Max && (number < r.Min || number >= r.Max) {
+		return nil, exception.NewErrorDescription(exception.InvalidFieldRange,
+			fmt.Sprintf(exception.InvalidFieldRangeString, key, strconv.FormatFloat(r.Min, 'f', -1, 64),
+				strconv.FormatFloat(r.Max, 'f', -1, 64)))
+	}
+
+	return parsed, nil
+}
+
+func (t FilterType) String() string {
+	switch t {
+	case FilterInt:
+		return "an integer"
+	case FilterFloat:
+		return "a number"
+	case FilterBool:
+		return "a boolean"
+	case FilterTime:
+		return "an RFC3339 timestamp or date"
+	default:
+		return "a string"
+	}
+}
+
+// splitList splits comma-separated values, skipping empty ones
+func splitList(list string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+func containsOperator(values []Operator, value Operator) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
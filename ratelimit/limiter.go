@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/persistence"
+)
+
+const (
+	// TokenBucket Allows bursts of Burst requests, refilled at Rate tokens per second
+	TokenBucket = "token_bucket"
+	// SlidingWindow Allows Rate * Window requests within any Window-long period
+	SlidingWindow = "sliding_window"
+)
+
+// Rule Rate limiting rule
+type Rule struct {
+	Algorithm string
+	// Rate Requests per second
+	Rate  float64
+	Burst int
+	// Window Sliding window length
+	Window time.Duration
+}
+
+// NewRule returns the rule set in alexandria.service.resiliency
+func NewRule(cfg *config.Kernel) Rule {
+	return Rule{
+		Algorithm: cfg.Resiliency.Limiter.Algorithm,
+		Rate:      cfg.Resiliency.RateLimit,
+		Burst:     cfg.Resiliency.RateBurst,
+		Window:    cfg.Resiliency.Limiter.Window,
+	}
+}
+
+// windowLimit returns the requests allowed per sliding window
+func (r Rule) windowLimit() int {
+	return int(math.Max(1, math.Round(r.Rate*r.Window.Seconds())))
+}
+
+// limit returns the limit reported to clients
+func (r Rule) limit() int {
+	if r.Algorithm == SlidingWindow {
+		return r.windowLimit()
+	}
+
+	return r.Burst
+}
+
+func (r Rule) validate() error {
+	switch r.Algorithm {
+	case TokenBucket:
+		if r.Rate <= 0 || r.Burst <= 0 {
+			return fmt.Errorf("token bucket requires a positive rate and burst")
+		}
+	case SlidingWindow:
+		if r.Rate <= 0 || r.Window <= 0 {
+			return fmt.Errorf("sliding window requires a positive rate and window")
+		}
+	default:
+		return fmt.Errorf("unknown rate limiting algorithm %q", r.Algorithm)
+	}
+
+	return nil
+}
+
+// Result Rate limiting decision
+type Result struct {
+	Allowed bool
+	// Limit Requests allowed per period
+	Limit     int
+	Remaining int
+	// Reset Time until the quota is fully restored
+	Reset time.Duration
+	// RetryAfter Time until the next request is allowed, zero if allowed
+	RetryAfter time.Duration
+}
+
+// Limiter Rate limits requests per key (e.g. user, IP or API key)
+type Limiter interface {
+	// Allow consumes a request from the key's quota
+	Allow(ctx context.Context, key string) (*Result, error)
+	// SetRule updates the rule at runtime
+	SetRule(rule Rule) error
+}
+
+// NewLimiter Obtain the rate limiter set in alexandria.service.resiliency, Redis limiters enforce
+// limits cluster-wide
+func NewLimiter(cfg *config.Kernel) (Limiter, func(), error) {
+	rule := NewRule(cfg)
+	if cfg.Resiliency.Limiter.Store != "redis" {
+		l, err := NewMemoryLimiter(rule)
+		return l, func() {}, err
+	}
+
+	client, cleanup, err := persistence.NewRedisPool(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err := NewRedisLimiter(client, cfg.Resiliency.Limiter.Prefix, rule)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	return l, cleanup, nil
+}
+
+// Watch updates the limiter's rule whenever alexandria.service.resiliency changes, returns a function
+// to stop watching
+func Watch(w *config.Watcher, l Limiter) func() {
+	return w.Subscribe("alexandria.service.resiliency", func(c config.Change) {
+		_ = l.SetRule(NewRule(c.Current))
+	})
+}
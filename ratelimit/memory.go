@@ -0,0 +1,170 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// maxKeys Keys kept before the least recently used one is evicted, evicted keys get a full quota
+	maxKeys = 10000
+	// evictScan Least recently used keys checked for full quotas on every request
+	evictScan = 2
+)
+
+// MemoryLimiter In-process limiter, limits are enforced per replica
+type MemoryLimiter struct {
+	mu    sync.Mutex
+	rule  Rule
+	keys  map[string]*list.Element
+	order *list.List
+	now   func() time.Time
+}
+
+type limiterEntry struct {
+	key    string
+	bucket *bucket
+	log    []time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryLimiter returns an in-process limiter
+func NewMemoryLimiter(rule Rule) (*MemoryLimiter, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+
+	return &MemoryLimiter{
+		rule:  rule,
+		keys:  make(map[string]*list.Element),
+		order: list.New(),
+		now:   time.Now,
+	}, nil
+}
+
+func (l *MemoryLimiter) SetRule(rule Rule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rule = rule
+	return nil
+}
+
+func (l *MemoryLimiter) Allow(_ context.Context, key string) (*Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evict(now)
+	entry := l.entry(key)
+
+	if l.rule.Algorithm == SlidingWindow {
+		return l.allowWindow(entry, now), nil
+	}
+	return l.allowBucket(entry, now), nil
+}
+
+// entry returns the state of the given key marked as the most recently used, the least recently
+// used key is dropped if the limiter is full
+func (l *MemoryLimiter) entry(key string) *limiterEntry {
+	if e, ok := l.keys[key]; ok {
+		l.order.MoveToFront(e)
+		return e.Value.(*limiterEntry)
+	}
+
+	entry := &limiterEntry{key: key}
+	l.keys[key] = l.order.PushFront(entry)
+	if l.order.Len() > maxKeys {
+		l.remove(l.order.Back())
+	}
+
+	return entry
+}
+
+func (l *MemoryLimiter) allowBucket(entry *limiterEntry, now time.Time) *Result {
+	burst := float64(l.rule.Burst)
+	if entry.bucket == nil {
+		entry.bucket = &bucket{tokens: burst, last: now}
+	}
+	b := entry.bucket
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.rule.Rate)
+	b.last = now
+
+	res := &Result{Limit: l.rule.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / l.rule.Rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((burst - b.tokens) / l.rule.Rate)
+
+	return res
+}
+
+func (l *MemoryLimiter) allowWindow(entry *limiterEntry, now time.Time) *Result {
+	limit := l.rule.windowLimit()
+	start := now.Add(-l.rule.Window)
+
+	// Drop requests out of the window, timestamps are sorted
+	log := entry.log
+	i := 0
+	for i < len(log) && !log[i].After(start) {
+		i++
+	}
+	log = log[i:]
+
+	res := &Result{Limit: limit}
+	if len(log) < limit {
+		log = append(log, now)
+		res.Allowed = true
+	} else {
+		res.RetryAfter = log[0].Add(l.rule.Window).Sub(now)
+	}
+	entry.log = log
+	res.Remaining = limit - len(log)
+	res.Reset = log[0].Add(l.rule.Window).Sub(now)
+
+	return res
+}
+
+// evict removes the least recently used keys with full quotas, only a few keys are checked per call
+// so requests take constant time
+func (l *MemoryLimiter) evict(now time.Time) {
+	for i := 0; i < evictScan; i++ {
+		e := l.order.Back()
+		if e == nil || !l.idle(e.Value.(*limiterEntry), now) {
+			return
+		}
+		l.remove(e)
+	}
+}
+
+// idle reports whether the key would get a full quota if it was forgotten
+func (l *MemoryLimiter) idle(entry *limiterEntry, now time.Time) bool {
+	if b := entry.bucket; b != nil && b.tokens+now.Sub(b.last).Seconds()*l.rule.Rate < float64(l.rule.Burst) {
+		return false
+	}
+	log := entry.log
+	return len(log) == 0 || !log[len(log)-1].After(now.Add(-l.rule.Window))
+}
+
+func (l *MemoryLimiter) remove(e *list.Element) {
+	l.order.Remove(e)
+	delete(l.keys, e.Value.(*limiterEntry).key)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,246 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alexandria-oss/core/auth"
+	"github.com/alexandria-oss/core/config"
+	"github.com/alexandria-oss/core/exception"
+	"github.com/alexandria-oss/core/httputil"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// KeyFunc Returns the rate limiting key of an HTTP request
+type KeyFunc func(r *http.Request) string
+
+// ContextKeyFunc Returns the rate limiting key of an endpoint request
+type ContextKeyFunc func(ctx context.Context) string
+
+// APIKeyVerifier Verifies an API key, returning the identifier its quota is tracked by (e.g. the
+// client ID the key was issued to) and whether the key is valid
+type APIKeyVerifier func(key string) (string, bool)
+
+// Global Shares a single quota between every request
+func Global(*http.Request) string {
+	return "global"
+}
+
+// ByIP Limits requests per connection remote address, forwarded headers are ignored. Use Keys.ByIP
+// behind reverse proxies
+func ByIP(r *http.Request) string {
+	return "ip:" + remoteHost(r.RemoteAddr)
+}
+
+// ByAPIKey Limits requests per verified API key sent in the given header, requests without it and
+// requests with keys rejected by verify are limited per remote address
+func ByAPIKey(header string, verify APIKeyVerifier) KeyFunc {
+	return apiKey(header, verify, ByIP)
+}
+
+// ContextByIP Limits endpoint requests per connection remote address, forwarded headers are ignored.
+// The context must be populated by go-kit's httptransport.PopulateRequestContext
+func ContextByIP(ctx context.Context) string {
+	remote, _ := ctx.Value(httptransport.ContextKeyRequestRemoteAddr).(string)
+	return "ip:" + remoteHost(remote)
+}
+
+// Keys Resolves rate limiting keys of authenticated and proxied requests, bearer tokens are verified
+// using the JWT secret and X-Forwarded-For headers are only read from trusted proxies
+type Keys struct {
+	secret  string
+	proxies []*net.IPNet
+}
+
+// NewKeys returns a key resolver verifying bearer tokens with the given secret and trusting the
+// X-Forwarded-For headers set by the given proxy CIDRs or IPs (e.g. 10.0.0.0/8)
+func NewKeys(secret string, trustedProxies ...string) (*Keys, error) {
+	proxies, err := parseProxies(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keys{secret: secret, proxies: proxies}, nil
+}
+
+// NewKeysFromConfig returns a key resolver using the kernel's JWT secret and rate limiter trusted
+// proxies
+func NewKeysFromConfig(cfg *config.Kernel) (*Keys, error) {
+	return NewKeys(cfg.Auth.JWTSecret, cfg.Resiliency.Limiter.TrustedProxies...)
+}
+
+func parseProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, exception.NewErrorDescription(exception.InvalidFieldFormat,
+					fmt.Sprintf(exception.InvalidFieldFormatString, "trusted_proxies", "CIDR or IP"))
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, exception.NewErrorDescription(exception.InvalidFieldFormat,
+				fmt.Sprintf(exception.InvalidFieldFormatString, "trusted_proxies", "CIDR or IP"))
+		}
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+// ByIP Limits requests per client IP, the rightmost untrusted X-Forwarded-For address is used if the
+// request comes from a trusted proxy
+func (k *Keys) ByIP(r *http.Request) string {
+	return "ip:" + k.clientIP(r.Header.Get("X-Forwarded-For"), r.RemoteAddr)
+}
+
+// ByUser Limits requests per verified bearer token user, anonymous requests and requests with
+// invalid tokens are limited per client IP
+func (k *Keys) ByUser(r *http.Request) string {
+	return k.userKey(r.Header.Get("Authorization"), func() string {
+		return k.ByIP(r)
+	})
+}
+
+// ByAPIKey Limits requests per verified API key sent in the given header, requests without it and
+// requests with keys rejected by verify are limited per client IP
+func (k *Keys) ByAPIKey(header string, verify APIKeyVerifier) KeyFunc {
+	return apiKey(header, verify, k.ByIP)
+}
+
+// ContextByIP Limits endpoint requests per client IP, the context must be populated by go-kit's
+// httptransport.PopulateRequestContext
+func (k *Keys) ContextByIP(ctx context.Context) string {
+	forwarded, _ := ctx.Value(httptransport.ContextKeyRequestXForwardedFor).(string)
+	remote, _ := ctx.Value(httptransport.ContextKeyRequestRemoteAddr).(string)
+	return "ip:" + k.clientIP(forwarded, remote)
+}
+
+// ContextByUser Limits endpoint requests per verified bearer token user, anonymous requests and
+// requests with invalid tokens are limited per client IP. The context must be populated by go-kit's
+// httptransport.PopulateRequestContext
+func (k *Keys) ContextByUser(ctx context.Context) string {
+	authorization, _ := ctx.Value(httptransport.ContextKeyRequestAuthorization).(string)
+	return k.userKey(authorization, func() string {
+		return k.ContextByIP(ctx)
+	})
+}
+
+func (k *Keys) userKey(authorization string, fallback func() string) string {
+	if claims, err := auth.VerifyBearerJWT(authorization, k.secret); err == nil && claims.Username != "" {
+		return "user:" + claims.Username
+	}
+
+	return fallback()
+}
+
+// clientIP returns the rightmost X-Forwarded-For hop not set by a trusted proxy, forwarded headers
+// are ignored if the remote address is not trusted
+func (k *Keys) clientIP(forwarded, remote string) string {
+	host := remoteHost(remote)
+	if forwarded == "" || !k.trusted(host) {
+		return host
+	}
+
+	hops := strings.Split(forwarded, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if !k.trusted(hop) {
+			return hop
+		}
+		host = hop
+	}
+
+	return host
+}
+
+func (k *Keys) trusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range k.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apiKey keys requests by the identifier of their verified API key, unverified keys are never used
+// as quota keys so clients cannot mint unlimited quotas by sending random values
+func apiKey(header string, verify APIKeyVerifier, fallback KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		if key := r.Header.Get(header); key != "" && verify != nil {
+			if id, ok := verify(key); ok && id != "" {
+				return "key:" + id
+			}
+		}
+		return fallback(r)
+	}
+}
+
+func remoteHost(remote string) string {
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+
+	return remote
+}
+
+// NewHTTPMiddleware rate limits HTTP handlers, setting the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset response headers. Limited requests get a 429 status and a Retry-After header.
+// Requests are allowed if the limiter fails
+func NewHTTPMiddleware(l Limiter, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := l.Allow(r.Context(), key(r))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(res.Reset.Seconds()))))
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+				httputil.ResponseErrJSON(r.Context(), exception.RateLimited, w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NewEndpointMiddleware rate limits go-kit endpoints, limited requests fail with
+// exception.RateLimited. Requests are allowed if the limiter fails
+func NewEndpointMiddleware(l Limiter, key ContextKeyFunc) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			res, err := l.Allow(ctx, key(ctx))
+			if err == nil && !res.Allowed {
+				return nil, exception.RateLimited
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
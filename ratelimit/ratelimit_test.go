@@ -0,0 +1,219 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexandria-oss/core/auth"
+	"github.com/alexandria-oss/core/exception"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dgrijalva/jwt-go"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	l, err := NewMemoryLimiter(Rule{Algorithm: TokenBucket, Rate: 1, Burst: 2})
+	assert.Nil(t, err)
+	l.now = func() time.Time { return now }
+
+	for i := 1; i >= 0; i-- {
+		res, err := l.Allow(ctx, "user:aruiz")
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+	res, _ := l.Allow(ctx, "user:aruiz")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 2*time.Second, res.Reset)
+
+	// Keys have their own quota and buckets refill over time
+	res, _ = l.Allow(ctx, "user:octavio")
+	assert.True(t, res.Allowed)
+	now = now.Add(time.Second)
+	res, _ = l.Allow(ctx, "user:aruiz")
+	assert.True(t, res.Allowed)
+
+	assert.Nil(t, l.SetRule(Rule{Algorithm: SlidingWindow, Rate: 0.5, Window: 4 * time.Second}))
+	for i := 0; i < 2; i++ {
+		res, _ = l.Allow(ctx, "ip:10.0.0.1")
+		assert.True(t, res.Allowed)
+		now = now.Add(time.Second)
+	}
+	res, _ = l.Allow(ctx, "ip:10.0.0.1")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 2, res.Limit)
+	assert.Equal(t, 2*time.Second, res.RetryAfter)
+	now = now.Add(2 * time.Second)
+	res, _ = l.Allow(ctx, "ip:10.0.0.1")
+	assert.True(t, res.Allowed)
+
+	assert.NotNil(t, l.SetRule(Rule{Algorithm: "leaky_bucket", Rate: 1}))
+
+	// Keys are capped, the least recently used one is evicted first
+	assert.Nil(t, l.SetRule(Rule{Algorithm: TokenBucket, Rate: 1, Burst: 1}))
+	l.Allow(ctx, "user:aruiz")
+	res, _ = l.Allow(ctx, "user:aruiz")
+	assert.False(t, res.Allowed)
+	for i := 0; i < maxKeys; i++ {
+		l.Allow(ctx, fmt.Sprintf("ip:10.0.%d.%d", i/256, i%256))
+	}
+	assert.Equal(t, maxKeys, len(l.keys))
+	assert.Equal(t, maxKeys, l.order.Len())
+	res, _ = l.Allow(ctx, "user:aruiz")
+	assert.True(t, res.Allowed)
+
+	// Keys with full quotas are dropped as requests come in
+	now = now.Add(time.Second)
+	for i := 0; i < 10; i++ {
+		l.Allow(ctx, "user:aruiz")
+	}
+	assert.Equal(t, maxKeys-evictScan*10, len(l.keys))
+}
+
+func TestRedisLimiter(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	for _, rule := range []Rule{
+		{Algorithm: TokenBucket, Rate: 0.1, Burst: 3},
+		{Algorithm: SlidingWindow, Rate: 0.1, Window: 30 * time.Second},
+	} {
+		l, err := NewRedisLimiter(client, "ratelimit:", rule)
+		assert.Nil(t, err)
+
+		for i := 2; i >= 0; i-- {
+			res, err := l.Allow(ctx, "user:aruiz")
+			assert.Nil(t, err)
+			assert.True(t, res.Allowed, rule.Algorithm)
+			assert.Equal(t, i, res.Remaining, rule.Algorithm)
+			assert.Equal(t, 3, res.Limit, rule.Algorithm)
+		}
+		res, err := l.Allow(ctx, "user:aruiz")
+		assert.Nil(t, err)
+		assert.False(t, res.Allowed, rule.Algorithm)
+		assert.True(t, res.RetryAfter > 0, rule.Algorithm)
+
+		// Limits are shared by every replica
+		replica, _ := NewRedisLimiter(client, "ratelimit:", rule)
+		res, _ = replica.Allow(ctx, "user:aruiz")
+		assert.False(t, res.Allowed, rule.Algorithm)
+		res, _ = replica.Allow(ctx, "user:octavio")
+		assert.True(t, res.Allowed, rule.Algorithm)
+	}
+}
+
+func TestNewHTTPMiddleware(t *testing.T) {
+	l, err := NewMemoryLimiter(Rule{Algorithm: TokenBucket, Rate: 1, Burst: 1})
+	assert.Nil(t, err)
+	verify := func(key string) (string, bool) {
+		if key == "alexandria" {
+			return "client-1", true
+		}
+		return "", false
+	}
+	h := NewHTTPMiddleware(l, ByAPIKey("X-API-Key", verify))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/author", nil)
+	r.Header.Set("X-API-Key", "alexandria")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Reset"))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// Requests without API key are limited per remote address, forwarded headers are ignored
+	r = httptest.NewRequest(http.MethodGet, "/v1/author", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	assert.Equal(t, "ip:192.0.2.1", ByAPIKey("X-API-Key", verify)(r))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// Unverified API keys share the remote address quota
+	r.Header.Set("X-API-Key", "random-1")
+	assert.Equal(t, "ip:192.0.2.1", ByAPIKey("X-API-Key", verify)(r))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	r.Header.Set("X-API-Key", "alexandria")
+	assert.Equal(t, "key:client-1", ByAPIKey("X-API-Key", verify)(r))
+	assert.Equal(t, "ip:192.0.2.1", ByAPIKey("X-API-Key", nil)(r))
+}
+
+func TestKeys(t *testing.T) {
+	_, err := NewKeys("alexandria", "10.0.0.0/33")
+	assert.True(t, errors.Is(err, exception.InvalidFieldFormat))
+	keys, err := NewKeys("alexandria", "10.0.0.0/8", "192.0.2.1")
+	assert.Nil(t, err)
+
+	// Forwarded headers are only read from trusted proxies, taking the rightmost untrusted hop
+	r := httptest.NewRequest(http.MethodGet, "/v1/author", nil)
+	r.RemoteAddr = "198.51.100.9:5123"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	assert.Equal(t, "ip:198.51.100.9", keys.ByIP(r))
+	r.RemoteAddr = "192.0.2.1:5123"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 198.51.100.9, 10.0.0.1")
+	assert.Equal(t, "ip:198.51.100.9", keys.ByIP(r))
+	r.Header.Set("X-API-Key", "random-1")
+	assert.Equal(t, "ip:198.51.100.9", keys.ByAPIKey("X-API-Key", func(string) (string, bool) {
+		return "", false
+	})(r))
+
+	sign := func(secret string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.IdentityClaims{
+			Username:       "aruiz",
+			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()},
+		}).SignedString([]byte(secret))
+		assert.Nil(t, err)
+		return "Bearer " + token
+	}
+	r.Header.Set("Authorization", sign("alexandria"))
+	assert.Equal(t, "user:aruiz", keys.ByUser(r))
+	// Tokens not signed with the secret are limited per IP
+	r.Header.Set("Authorization", sign("spoofed"))
+	assert.Equal(t, "ip:198.51.100.9", keys.ByUser(r))
+
+	ctx := context.WithValue(context.Background(), httptransport.ContextKeyRequestRemoteAddr, "10.1.2.3:5123")
+	ctx = context.WithValue(ctx, httptransport.ContextKeyRequestXForwardedFor, "203.0.113.7")
+	assert.Equal(t, "ip:203.0.113.7", keys.ContextByUser(ctx))
+	ctx = context.WithValue(ctx, httptransport.ContextKeyRequestAuthorization, sign("alexandria"))
+	assert.Equal(t, "user:aruiz", keys.ContextByUser(ctx))
+}
+
+func TestNewEndpointMiddleware(t *testing.T) {
+	l, err := NewMemoryLimiter(Rule{Algorithm: TokenBucket, Rate: 1, Burst: 1})
+	assert.Nil(t, err)
+	e := NewEndpointMiddleware(l, ContextByIP)(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+
+	ctx := context.WithValue(context.Background(), httptransport.ContextKeyRequestRemoteAddr, "192.0.2.1:5123")
+	assert.Equal(t, "ip:192.0.2.1", ContextByIP(ctx))
+	res, err := e(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", res)
+	_, err = e(ctx, nil)
+	assert.True(t, errors.Is(err, exception.RateLimited))
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/google/uuid"
+)
+
+var (
+	// tokenBucketScript refills and consumes the key's bucket, rate is given in tokens per millisecond.
+	// Returns {allowed, remaining, retry after ms, reset ms}
+	tokenBucketScript = redis.NewScript(`local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate))
+return {allowed, math.floor(tokens), retry, math.ceil((burst - tokens) / rate)}`)
+	// slidingWindowScript logs requests in a sorted set scored by timestamp.
+	// Returns {allowed, remaining, retry after ms, reset ms}
+	slidingWindowScript = redis.NewScript(`local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call("PEXPIRE", KEYS[1], window)
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+local reset = tonumber(oldest[2]) + window - now
+local retry = 0
+if allowed == 0 then
+	retry = reset
+end
+return {allowed, limit - count, retry, reset}`)
+)
+
+// RedisLimiter Limiter backed by Redis, limits are enforced cluster-wide
+type RedisLimiter struct {
+	client redis.UniversalClient
+	prefix string
+
+	mu   sync.RWMutex
+	rule Rule
+}
+
+// NewRedisLimiter returns a Redis limiter prepending prefix to every key
+func NewRedisLimiter(client redis.UniversalClient, prefix string, rule Rule) (*RedisLimiter, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+
+	return &RedisLimiter{
+		client: client,
+		prefix: prefix,
+		rule:   rule,
+	}, nil
+}
+
+func (l *RedisLimiter) SetRule(rule Rule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rule = rule
+	return nil
+}
+
+func (l *RedisLimiter) Allow(_ context.Context, key string) (*Result, error) {
+	l.mu.RLock()
+	rule := l.rule
+	l.mu.RUnlock()
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	var cmd *redis.Cmd
+	if rule.Algorithm == SlidingWindow {
+		cmd = slidingWindowScript.Run(l.client, []string{l.prefix + "sw:" + key}, now,
+			rule.Window.Milliseconds(), rule.windowLimit(), uuid.New().String())
+	} else {
+		cmd = tokenBucketScript.Run(l.client, []string{l.prefix + "tb:" + key},
+			strconv.FormatFloat(rule.Rate/1000, 'f', -1, 64), rule.Burst, now)
+	}
+	reply, err := cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+
+	replies, _ := reply.([]interface{})
+	values := make([]int64, 4)
+	for i := 0; i < len(replies) && i < len(values); i++ {
+		values[i], _ = replies[i].(int64)
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      rule.limit(),
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
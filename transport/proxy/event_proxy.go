@@ -18,17 +18,19 @@ type Event struct {
 }
 
 func NewEvent(ctx context.Context, cfg *config.Kernel, consumers ...Consumer) (*Event, func(), error) {
-	ctxS, _ := context.WithCancel(ctx)
+	ctxS, cancel := context.WithCancel(ctx)
 
 	srv := eventbus.NewServer(ctxS)
 	clean := func() {
 		srv.Close()
+		cancel()
 	}
 
 	proxy := &Event{srv, ctx, cfg, consumers}
 
 	err := proxy.mapRoutes()
 	if err != nil {
+		cancel()
 		return nil, nil, err
 	}
 